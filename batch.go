@@ -0,0 +1,123 @@
+package coincross
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BatchOptions configures CancelAll and PlaceOrders.
+type BatchOptions struct {
+	// The maximum number of calls in flight, 4 if not set.
+	Concurrency int
+}
+
+// CancelResult is the result of cancelling an order.
+type CancelResult struct {
+	Order Order
+	Err   error
+}
+
+// PlaceResult is the result of placing an order.
+type PlaceResult struct {
+	Request OrderRequest
+	Order   Order
+	Err     error
+}
+
+// BatchError tells that some calls of a batch have failed. The results tell
+// which ones.
+type BatchError struct {
+	Failed, Total int
+	// The errors of the failed calls, in order.
+	Errs []error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d failed, first: %v", e.Failed, e.Total, e.Errs[0])
+}
+
+// Unwrap allows errors.Is to match any of the errors.
+func (e *BatchError) Unwrap() []error {
+	return e.Errs
+}
+
+// CancelAll cancels the open orders of the pair, or all the open orders if
+// pair is ALL. It returns a result for every order, and a *BatchError if any
+// of them fails.
+func CancelAll(c Client, pair Pair, opts BatchOptions) ([]CancelResult, error) {
+	orders, err := c.Orders()
+	if err != nil {
+		return nil, err
+	}
+	var results []CancelResult
+	for _, o := range orders {
+		if pair == ALL || o.Pair == pair {
+			results = append(results, CancelResult{Order: o})
+		}
+	}
+	batch(len(results), opts.Concurrency, func(i int) {
+		r := &results[i]
+		ok, err := c.Cancel(r.Order.Id)
+		if err == nil && !ok {
+			err = fmt.Errorf("order %d not cancelled", r.Order.Id)
+		}
+		r.Err = err
+	})
+	errs := make([]error, len(results))
+	for i, r := range results {
+		errs[i] = r.Err
+	}
+	return results, batchError(errs)
+}
+
+// PlaceOrders places the orders with PlaceOrder. It returns a result for
+// every request, and a *BatchError if any of them fails. The orders are
+// placed concurrently, so their order on the exchange is not defined. Some
+// exchanges still place them one at a time, such as BTCChina, which finds
+// the id of a new order by comparing the open orders before and after.
+func PlaceOrders(c Client, reqs []OrderRequest, opts BatchOptions) ([]PlaceResult, error) {
+	results := make([]PlaceResult, len(reqs))
+	batch(len(reqs), opts.Concurrency, func(i int) {
+		order, err := PlaceOrder(c, reqs[i])
+		results[i] = PlaceResult{reqs[i], order, err}
+	})
+	errs := make([]error, len(results))
+	for i, r := range results {
+		errs[i] = r.Err
+	}
+	return results, batchError(errs)
+}
+
+// batch calls f for 0 to n-1, with up to concurrency calls at once.
+func batch(n, concurrency int, f func(i int)) {
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// batchError returns a *BatchError of the non-nil errors, or nil if none.
+func batchError(errs []error) error {
+	e := &BatchError{Total: len(errs)}
+	for _, err := range errs {
+		if err != nil {
+			e.Failed++
+			e.Errs = append(e.Errs, err)
+		}
+	}
+	if e.Failed == 0 {
+		return nil
+	}
+	return e
+}
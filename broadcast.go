@@ -0,0 +1,248 @@
+package coincross
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy tells what to do when the buffer of a subscriber is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for the subscriber, holding up the others, until
+	// the subscription or the broadcaster is closed.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest trade in the buffer.
+	OverflowDropOldest
+	// OverflowDisconnect closes the subscription with ErrSlowSubscriber.
+	OverflowDisconnect
+)
+
+// ErrSlowSubscriber is the error of a subscription disconnected because
+// its buffer was full.
+var ErrSlowSubscriber = errors.New("slow subscriber")
+
+// SubscribeOptions configures Broadcaster.Subscribe.
+type SubscribeOptions struct {
+	// The size of the buffer, 100 if not set.
+	Buffer   int
+	Overflow OverflowPolicy
+	// Replay delivers up to this many recent trades first, as far as the
+	// broadcaster keeps them. Negative values are taken as 0.
+	Replay int
+}
+
+// Broadcaster delivers the trades of a Streamer to many subscribers.
+// The Errors and Gaps of the streamer can still be received from it.
+type Broadcaster struct {
+	streamer *Streamer
+	size     int
+	closing  chan struct{}
+	done     chan struct{}
+	once     sync.Once
+
+	mu      sync.Mutex
+	subs    map[*Subscription]bool
+	history []Trade
+	stopped bool
+	err     error
+}
+
+// NewBroadcaster starts delivering the trades of streamer, keeping the
+// recent history trades for replay.
+func NewBroadcaster(streamer *Streamer, history int) *Broadcaster {
+	b := &Broadcaster{
+		streamer: streamer,
+		size:     history,
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+		subs:     make(map[*Subscription]bool),
+	}
+	go b.run()
+	return b
+}
+
+// Subscribe attaches a new subscriber.
+// If the broadcaster has stopped, the subscription is closed after replay.
+func (b *Broadcaster) Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 100
+	}
+	if opts.Replay < 0 {
+		opts.Replay = 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	replay := b.history
+	if opts.Replay < len(replay) {
+		replay = replay[len(replay)-opts.Replay:]
+	}
+	size := opts.Buffer
+	if len(replay) > size {
+		size = len(replay)
+	}
+	c := make(chan Trade, size)
+	for _, t := range replay {
+		c <- t
+	}
+	sub := &Subscription{C: c, c: c, b: b, policy: opts.Overflow, closing: make(chan struct{})}
+	if b.stopped {
+		sub.err, sub.closed = b.err, true
+		close(c)
+	} else {
+		b.subs[sub] = true
+	}
+	return sub
+}
+
+// Close stops the streamer, and waits until all the subscriptions are closed.
+func (b *Broadcaster) Close() error {
+	b.once.Do(func() { close(b.closing) })
+	b.streamer.Close()
+	<-b.done
+	return nil
+}
+
+func (b *Broadcaster) run() {
+	defer close(b.done)
+	for t := range b.streamer.C {
+		b.mu.Lock()
+		if b.size > 0 {
+			if len(b.history) >= b.size {
+				b.history = append(b.history[:0], b.history[len(b.history)-b.size+1:]...)
+			}
+			b.history = append(b.history, t)
+		}
+		subs := b.subscribers()
+		b.mu.Unlock()
+
+		// Sent without holding b.mu, which a blocking send would hold up.
+		for _, sub := range subs {
+			if !sub.send(t) {
+				b.remove(sub, ErrSlowSubscriber)
+			}
+		}
+	}
+
+	b.mu.Lock()
+	b.stopped = true
+	b.err = b.streamer.Err()
+	subs := b.subscribers()
+	b.mu.Unlock()
+	for _, sub := range subs {
+		b.remove(sub, b.err)
+	}
+}
+
+// subscribers returns the current subscribers. b.mu must be held.
+func (b *Broadcaster) subscribers() []*Subscription {
+	subs := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// remove detaches sub, closing it with err if it is still attached.
+func (b *Broadcaster) remove(sub *Subscription, err error) {
+	b.mu.Lock()
+	attached := b.subs[sub]
+	if attached {
+		delete(b.subs, sub)
+		sub.err = err
+	}
+	b.mu.Unlock()
+	if attached {
+		sub.close()
+	}
+}
+
+// Subscription is a subscriber of a Broadcaster.
+type Subscription struct {
+	// C delivers the trades. It is closed when the subscription is closed.
+	C <-chan Trade
+
+	c       chan Trade
+	b       *Broadcaster
+	policy  OverflowPolicy
+	closing chan struct{}
+	once    sync.Once
+	dropped int64
+	// Guarded by b.mu.
+	err error
+
+	// Held while sending to c, so that c is not closed meanwhile.
+	mu     sync.Mutex
+	closed bool
+}
+
+// send delivers t by the policy, and tells whether the subscriber keeps up.
+func (s *Subscription) send(t Trade) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	switch s.policy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.c <- t:
+				return true
+			default:
+			}
+			select {
+			case <-s.c:
+				atomic.AddInt64(&s.dropped, 1)
+			default:
+			}
+		}
+	case OverflowDisconnect:
+		select {
+		case s.c <- t:
+			return true
+		default:
+			return false
+		}
+	}
+	select {
+	case s.c <- t:
+	case <-s.closing:
+	case <-s.b.closing:
+	}
+	return true
+}
+
+// close closes c, once no send is in progress.
+func (s *Subscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.c)
+	}
+}
+
+// Dropped returns the number of trades dropped by OverflowDropOldest.
+func (s *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Err returns the error that closed the subscription, once C is closed:
+// ErrSlowSubscriber, or the error that stopped the streamer.
+// It returns nil if the subscription is closed by Close.
+func (s *Subscription) Err() error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	return s.err
+}
+
+// Close detaches the subscriber. It is safe to call Close more than once.
+func (s *Subscription) Close() error {
+	// Stops the send in progress first.
+	s.once.Do(func() { close(s.closing) })
+	s.b.remove(s, nil)
+	return nil
+}
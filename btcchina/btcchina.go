@@ -2,14 +2,21 @@
 package btcchina
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	s "github.com/thinxer/coincross"
 )
 
+// The name registered in coincross.
+const exchange = "btcchina"
+
 const (
 	ENDPOINT  = "https://api.btcchina.com/api_trade_v1.php"
 	HISTORY   = "https://data.btcchina.com/data/historydata"
@@ -17,21 +24,133 @@ const (
 	TICKER    = "https://data.btcchina.com/data/ticker"
 )
 
+// Endpoints are the URLs of the BTCChina APIs.
+type Endpoints struct {
+	// The JSON-RPC endpoint for the trade API.
+	Trade string
+	// The public data API. Orderbook is unused, as the market depth is
+	// taken from the trade API.
+	History, Orderbook, Ticker string
+}
+
+// DefaultEndpoints are the official endpoints.
+var DefaultEndpoints = Endpoints{
+	Trade:     ENDPOINT,
+	History:   HISTORY,
+	Orderbook: ORDERBOOK,
+	Ticker:    TICKER,
+}
+
+// Rebase returns the endpoints with the scheme and host replaced by base.
+func (e Endpoints) Rebase(base string) Endpoints {
+	return Endpoints{
+		Trade:     s.RebaseURL(e.Trade, base),
+		History:   s.RebaseURL(e.History, base),
+		Orderbook: s.RebaseURL(e.Orderbook, base),
+		Ticker:    s.RebaseURL(e.Ticker, base),
+	}
+}
+
+// Set replaces the endpoint of the name, which is one of "trade", "history",
+// "orderbook" and "ticker".
+func (e *Endpoints) Set(name, url string) error {
+	switch name {
+	case "trade":
+		e.Trade = url
+	case "history":
+		e.History = url
+	case "orderbook":
+		e.Orderbook = url
+	case "ticker":
+		e.Ticker = url
+	default:
+		return fmt.Errorf("%s: unknown endpoint %q", exchange, name)
+	}
+	return nil
+}
+
+// DefaultRateLimits are conservative limits for BTCChina.
+var DefaultRateLimits = s.RateLimits{
+	Public:  s.Limit{Rate: 1, Burst: 3},
+	Private: s.Limit{Rate: 1, Burst: 5},
+}
+
 type BTCChina struct {
 	apikey string
 	secret []byte
 	client *http.Client
+	// The transport under the rate limiter.
+	base      http.RoundTripper
+	endpoints Endpoints
+	tonce     s.NonceSource
+	limiter   *s.RateLimiter
 }
 
+// BTCChina uses microseconds as tonces.
+var defaultTonce = s.NewNonceCounter(s.UnixMicros)
+
+// New creates a client. A nil transport means http.DefaultTransport.
 func New(apikey, secret string, transport *http.Transport) *BTCChina {
-	return &BTCChina{apikey, []byte(secret), &http.Client{
-		Transport: transport,
-	}}
+	config := &s.Config{APIKey: apikey, Secret: secret}
+	if transport != nil {
+		config.Transport = transport
+	}
+	bc, _ := NewWithConfig(config)
+	return bc
+}
+
+// NewWithConfig creates a client with all the options of coincross.Config.
+// The endpoints in config.Endpoints are named as in Endpoints.Set.
+func NewWithConfig(config *s.Config) (*BTCChina, error) {
+	bc := &BTCChina{
+		apikey:    config.APIKey,
+		secret:    []byte(config.Secret),
+		client:    new(http.Client),
+		base:      config.Transport,
+		endpoints: DefaultEndpoints.Rebase(config.BaseURL),
+		tonce:     defaultTonce,
+	}
+	for name, url := range config.Endpoints {
+		if err := bc.endpoints.Set(name, url); err != nil {
+			return nil, err
+		}
+	}
+	if config.Logger != nil {
+		bc.base = s.LogTransport(bc.base, config.Logger)
+	}
+	if config.NonceSource != nil {
+		bc.tonce = config.NonceSource
+	}
+	limiter := config.RateLimiter
+	if limiter == nil {
+		limiter = s.SharedRateLimiter(exchange, config.APIKey, DefaultRateLimits, s.Block)
+	}
+	bc.SetRateLimiter(limiter)
+	return bc, nil
+}
+
+// SetRateLimiter replaces the rate limiter. By default, the clients of the
+// same key share a limiter with DefaultRateLimits in Block mode.
+func (bc *BTCChina) SetRateLimiter(limiter *s.RateLimiter) {
+	bc.limiter = limiter
+	bc.client.Transport = s.RateLimitTransport(bc.base, limiter)
+}
+
+// SetNonceSource replaces the tonce source, which is shared by all the
+// clients in the process by default. Use a FileNonce if the same key is used
+// by multiple processes.
+func (bc *BTCChina) SetNonceSource(tonce s.NonceSource) {
+	bc.tonce = tonce
+}
+
+// SetEndpoints replaces the endpoints, which are DefaultEndpoints by default.
+func (bc *BTCChina) SetEndpoints(endpoints Endpoints) {
+	bc.endpoints = endpoints
 }
 
 type AccountInfo struct {
 	Balance, Frozen map[string]struct {
-		Amount           string
+		Amount           s.Decimal
 		AmountInteger    string `json:"amount_integer"`
 		Currency, Symbol string
 		AmountDecimal    int `json:"amount_decimal"`
@@ -48,112 +167,365 @@ type AccountInfo struct {
 }
 
 func (bc *BTCChina) AccountInfo() (info *AccountInfo, err error) {
+	return bc.accountInfo(context.Background())
+}
+
+func (bc *BTCChina) accountInfo(ctx context.Context) (info *AccountInfo, err error) {
 	info = new(AccountInfo)
-	err = bc.request("getAccountInfo", []interface{}{}, info)
+	err = bc.request(ctx, "getAccountInfo", []interface{}{}, info)
 	return
 }
 
-func (bc *BTCChina) Balance() (balance map[s.Symbol]float64, err error) {
-	rai, err := bc.AccountInfo()
+func (bc *BTCChina) Balance() (map[s.Symbol]s.Decimal, error) {
+	return bc.BalanceContext(context.Background())
+}
+
+func (bc *BTCChina) BalanceContext(ctx context.Context) (balance map[s.Symbol]s.Decimal, err error) {
+	rai, err := bc.accountInfo(ctx)
 	if err == nil {
-		balance = make(map[s.Symbol]float64)
-		balance[s.CNY], _ = strconv.ParseFloat(rai.Balance["cny"].Amount, 64)
-		balance[s.BTC], _ = strconv.ParseFloat(rai.Balance["btc"].Amount, 64)
+		balance = make(map[s.Symbol]s.Decimal)
+		balance[s.CNY] = rai.Balance["cny"].Amount
+		balance[s.BTC] = rai.Balance["btc"].Amount
 	}
 	return
 }
 
-func (bc *BTCChina) Trade(tradeType s.TradeType, _ s.Pair, price, amount float64) (orderId int64, err error) {
-	var success bool
+func (bc *BTCChina) Trade(tradeType s.TradeType, pair s.Pair, price, amount s.Decimal) (int64, error) {
+	return bc.TradeContext(context.Background(), tradeType, pair, price, amount)
+}
+
+// TradeContext places an order.
+//
+// BTCChina does not return the id of the placed order, so it is recovered by
+// comparing the orders before and after the placement, and the orders of the
+// key are placed one at a time in the process. If the id cannot be
+// determined, for example because another process placed an order of the same
+// kind at the same time, the order is still placed, but -1 and
+// ErrAmbiguousOrderId are returned.
+func (bc *BTCChina) TradeContext(ctx context.Context, tradeType s.TradeType, _ s.Pair, price, amount s.Decimal) (int64, error) {
+	order, err := bc.place(ctx, tradeType, price, amount)
+	if err != nil {
+		return -1, err
+	}
+	return order.Id, nil
+}
+
+// place places an order, or a market order if price is nil, and recovers it
+// as TradeContext does.
+func (bc *BTCChina) place(ctx context.Context, tradeType s.TradeType, price interface{}, amount s.Decimal) (order s.Order, err error) {
+	var method string
 	switch tradeType {
 	case s.Sell:
-		err = bc.request("sellOrder", []interface{}{price, amount}, &success)
+		method = "sellOrder"
 	case s.Buy:
-		err = bc.request("buyOrder", []interface{}{price, amount}, &success)
+		method = "buyOrder"
+	default:
+		return order, fmt.Errorf("%w: unknown trade type %d", s.ErrInvalidOrder, tradeType)
+	}
+	// The id is found by comparing the orders before and after, so the
+	// orders of the key are placed one at a time. This lock is held across
+	// requests, so it is not the one taken by request.
+	lock := s.KeyLock(exchange+"/place", bc.apikey)
+	if err = lock.Lock(ctx); err != nil {
+		return
 	}
+	defer lock.Unlock()
+	before, err := bc.orders(ctx, false)
+	if err != nil {
+		return
+	}
+	var success bool
+	err = bc.request(ctx, method, []interface{}{price, amount}, &success)
 	if err == nil && !success {
-		err = s.TradeError(fmt.Errorf("place order failed"))
+		err = &s.ExchangeError{Exchange: exchange, Method: method, Message: "place order failed"}
+	}
+	if err != nil {
+		return
+	}
+	after, err := bc.orders(ctx, false)
+	if err != nil {
+		return order, fmt.Errorf("%w: %v", s.ErrAmbiguousOrderId, err)
+	}
+	limit, _ := price.(s.Decimal)
+	order, err = s.FindPlacedOrder(before, after, tradeType, s.BTC_CNY, limit, amount)
+	if err == s.ErrOrderNotFound {
+		err = fmt.Errorf("%w: %v", s.ErrAmbiguousOrderId, err)
 	}
-	// TODO
-	orderId = -1
 	return
 }
 
-func (bc *BTCChina) Cancel(orderId int64) (success bool, err error) {
-	err = bc.request("cancelOrder", []interface{}{orderId}, &success)
+// PlaceOrder places a limit or market order. IOC is emulated by cancelling
+// the remainder, and market orders are always IOC. FOK and post-only orders
+// are not supported. If the id of an IOC order cannot be found, the error
+// matches ErrAmbiguousOrderId, and the remainder may still be open.
+func (bc *BTCChina) PlaceOrder(req s.OrderRequest) (s.Order, error) {
+	return bc.PlaceOrderContext(context.Background(), req)
+}
+
+func (bc *BTCChina) PlaceOrderContext(ctx context.Context, req s.OrderRequest) (order s.Order, err error) {
+	if err = req.Check(); err != nil {
+		return
+	}
+	if req.Pair != s.BTC_CNY {
+		return order, fmt.Errorf("%w: unknown pair %s", s.ErrInvalidOrder, req.Pair)
+	}
+	if req.TimeInForce != s.GTC && req.TimeInForce != s.IOC {
+		return order, s.UnsupportedOrder(exchange, "%s orders not supported", req.TimeInForce)
+	}
+	if req.Type == s.MarketOrder {
+		return bc.place(ctx, req.Side, nil, req.Amount)
+	}
+	order, err = bc.place(ctx, req.Side, req.Price, req.Amount)
+	if req.TimeInForce != s.IOC {
+		return
+	}
+	if errors.Is(err, s.ErrAmbiguousOrderId) {
+		// Without the id, the remainder cannot be cancelled.
+		return order, fmt.Errorf("%w: the remainder of the IOC order may still be open", err)
+	}
+	if err == nil {
+		return s.CancelRemainder(bc, order)
+	}
+	return
+}
+
+func (bc *BTCChina) Cancel(orderId int64) (bool, error) {
+	return bc.CancelContext(context.Background(), orderId)
+}
+
+func (bc *BTCChina) CancelContext(ctx context.Context, orderId int64) (success bool, err error) {
+	err = bc.request(ctx, "cancelOrder", []interface{}{orderId}, &success)
 	return
 }
 
-func (bc *BTCChina) Transactions(limit int) (transactions []s.Transaction, err error) {
+func (bc *BTCChina) Transactions(limit int) ([]s.Transaction, error) {
+	return bc.TransactionsContext(context.Background(), limit)
+}
+
+func (bc *BTCChina) TransactionsContext(ctx context.Context, limit int) (transactions []s.Transaction, err error) {
+	list, err := bc.transactions(ctx, "all", limit)
+	for _, tr := range list {
+		var t s.Transaction
+		t.Id = tr.Id
+		t.Timestamp = tr.Date
+		t.Amounts = make(map[s.Symbol]s.Decimal)
+		t.Amounts[s.BTC] = tr.BtcAmount
+		t.Amounts[s.CNY] = tr.CnyAmount
+		t.Descritpion = tr.Type
+		t.Kind = transactionKind(tr.Type)
+		transactions = append(transactions, t)
+	}
+	return
+}
+
+// transactionKind returns the kind of a transaction type of getTransactions.
+func transactionKind(kind string) s.TransactionKind {
+	switch {
+	case kind == "buybtc":
+		return s.TransactionBuy
+	case kind == "sellbtc":
+		return s.TransactionSell
+	case kind == "tradefee":
+		return s.TransactionFee
+	case strings.HasPrefix(kind, "fund"):
+		return s.TransactionDeposit
+	case strings.HasPrefix(kind, "withdraw"):
+		return s.TransactionWithdrawal
+	}
+	return s.TransactionUnknown
+}
+
+type transaction struct {
+	Id        int64
+	Type      string
+	BtcAmount s.Decimal `json:"btc_amount"`
+	CnyAmount s.Decimal `json:"cny_amount"`
+	Date      int64
+}
+
+// transactions calls getTransactions. Additional parameters, such as since,
+// may be given after limit.
+func (bc *BTCChina) transactions(ctx context.Context, kind string, limit int, params ...interface{}) ([]transaction, error) {
 	var response struct {
-		Transaction []struct {
-			Id        int64
-			Type      string
-			BtcAmount string `json:"btc_amount"`
-			CnyAmount string `json:"cny_amount"`
-			Date      int64
-		}
+		Transaction []transaction
+	}
+	err := bc.request(ctx, "getTransactions", append([]interface{}{kind, limit}, params...), &response)
+	return response.Transaction, err
+}
+
+// Fills returns up to 1000 of your trades since the time, as found in the
+// transactions. BTCChina does not tell the orders and liquidity of the
+// trades, and records the fees as separate transactions, which are matched to
+// the trade of the same time, or of the nearest id.
+func (bc *BTCChina) Fills(pair s.Pair, since int64) ([]s.Fill, error) {
+	return bc.FillsContext(context.Background(), pair, since)
+}
+
+func (bc *BTCChina) FillsContext(ctx context.Context, pair s.Pair, since int64) (fills []s.Fill, err error) {
+	if pair != s.ALL && pair != s.BTC_CNY {
+		return
 	}
-	if err = bc.request("getTransactions", []interface{}{"all", limit}, &response); err == nil {
-		for _, tr := range response.Transaction {
-			var t s.Transaction
-			t.Id = tr.Id
-			t.Timestamp = tr.Date
-			t.Amounts = make(map[s.Symbol]float64)
-			t.Amounts[s.BTC], _ = strconv.ParseFloat(tr.BtcAmount, 64)
-			t.Amounts[s.CNY], _ = strconv.ParseFloat(tr.CnyAmount, 64)
-			t.Descritpion = tr.Type
-			transactions = append(transactions, t)
+	list, err := bc.transactions(ctx, "all", 1000, 0, since, "time")
+	var fees []transaction
+	for _, tr := range list {
+		f := s.Fill{TradeId: tr.Id, Pair: s.BTC_CNY, Amount: tr.BtcAmount.Abs(), Timestamp: tr.Date}
+		switch tr.Type {
+		case "buybtc":
+			f.Side = s.Buy
+		case "sellbtc":
+			f.Side = s.Sell
+		case "tradefee":
+			fees = append(fees, tr)
+			continue
+		default:
+			continue
 		}
+		if f.Amount != 0 {
+			f.Price = tr.CnyAmount.Abs().Div(f.Amount)
+		}
+		fills = append(fills, f)
+	}
+	sort.Slice(fills, func(i, j int) bool { return fills[i].TradeId < fills[j].TradeId })
+	for _, fee := range fees {
+		addFee(fills, fee)
 	}
 	return
 }
 
-func (bc *BTCChina) Orders() (orders []s.Order, err error) {
-	var response struct {
-		Order []struct {
-			Id             int64
-			Type           s.TradeType
-			Price          string
-			Currency       string
-			Amount         string
-			AmountOriginal string `json:"amount_original"`
-			Date           int64
-			Status         string
+// addFee adds a tradefee transaction to the fill of the same time, or of the
+// nearest id if none.
+func addFee(fills []s.Fill, fee transaction) {
+	sameTime := func(f s.Fill) bool { return f.Timestamp == fee.Date }
+	best := -1
+	for i, f := range fills {
+		switch {
+		case best < 0:
+		case sameTime(f) != sameTime(fills[best]):
+			if !sameTime(f) {
+				continue
+			}
+		case distance(f.TradeId, fee.Id) >= distance(fills[best].TradeId, fee.Id):
+			continue
 		}
+		best = i
+	}
+	if best < 0 {
+		return
+	}
+	amount, symbol := fee.BtcAmount.Abs(), s.BTC
+	if amount == 0 {
+		amount, symbol = fee.CnyAmount.Abs(), s.CNY
+	}
+	f := &fills[best]
+	if f.Fee != 0 && f.FeeSymbol != symbol {
+		return
+	}
+	f.Fee, f.FeeSymbol = f.Fee+amount, symbol
+}
+
+func distance(a, b int64) int64 {
+	if a < b {
+		return b - a
 	}
-	if err = bc.request("getOrders", []interface{}{}, &response); err == nil {
+	return a - b
+}
+
+func (bc *BTCChina) Orders() ([]s.Order, error) {
+	return bc.OrdersContext(context.Background())
+}
+
+func (bc *BTCChina) OrdersContext(ctx context.Context) ([]s.Order, error) {
+	return bc.orders(ctx, true)
+}
+
+// orders returns the open orders, or all the recent orders if openonly is false.
+func (bc *BTCChina) orders(ctx context.Context, openonly bool) (orders []s.Order, err error) {
+	var response struct {
+		Order []orderInfo
+	}
+	if err = bc.request(ctx, "getOrders", []interface{}{openonly}, &response); err == nil {
 		for _, order := range response.Order {
-			var o s.Order
-			o.Id = order.Id
-			o.Type = order.Type
-			o.Price, _ = strconv.ParseFloat(order.Price, 64)
-			o.Amount, _ = strconv.ParseFloat(order.AmountOriginal, 64)
-			o.Remain, _ = strconv.ParseFloat(order.Amount, 64)
-			o.Pair = s.BTC_CNY
-			o.Timestamp = order.Date
-			orders = append(orders, o)
+			orders = append(orders, order.order())
+		}
+	}
+	return
+}
+
+// GetOrder returns the order of the id, including the closed ones.
+func (bc *BTCChina) GetOrder(orderId int64) (s.Order, error) {
+	return bc.GetOrderContext(context.Background(), orderId)
+}
+
+func (bc *BTCChina) GetOrderContext(ctx context.Context, orderId int64) (order s.Order, err error) {
+	var response struct {
+		Order orderInfo
+	}
+	if err = bc.request(ctx, "getOrder", []interface{}{orderId}, &response); err == nil {
+		order = response.Order.order()
+	}
+	return
+}
+
+// orderInfo is an order in the replies of getOrders and getOrder.
+type orderInfo struct {
+	Id             int64
+	Type           s.TradeType
+	Price          s.Decimal
+	Currency       string
+	Amount         s.Decimal
+	AmountOriginal s.Decimal `json:"amount_original"`
+	Date           int64
+	Status         string
+}
+
+func (info orderInfo) order() (o s.Order) {
+	o.Id = info.Id
+	o.Type = info.Type
+	o.Price = info.Price
+	o.Amount = info.AmountOriginal
+	o.Remain = info.Amount
+	o.Pair = s.BTC_CNY
+	o.Timestamp = info.Date
+	switch info.Status {
+	case "open", "pending":
+		o.Status = s.StatusOpen
+		if o.Remain < o.Amount {
+			o.Status = s.StatusPartial
 		}
+	case "closed":
+		o.Status = s.StatusFilled
+	case "cancelled":
+		o.Status = s.StatusCancelled
+	case "error", "insufficient_balance":
+		o.Status = s.StatusRejected
 	}
 	return
 }
 
-func (bc *BTCChina) Orderbook(_ s.Pair, limit int) (orderbook *s.Orderbook, err error) {
+func (bc *BTCChina) Orderbook(pair s.Pair, limit int) (*s.Orderbook, error) {
+	return bc.OrderbookContext(context.Background(), pair, limit)
+}
+
+func (bc *BTCChina) OrderbookContext(ctx context.Context, _ s.Pair, limit int) (orderbook *s.Orderbook, err error) {
 	var response struct {
 		MarketDepth struct {
 			Ask, Bid []struct {
-				Price, Amount float64
+				Price, Amount s.Decimal
 			}
 		} `json:"market_depth"`
 	}
-	err = bc.request("getMarketDepth2", []interface{}{limit}, &response)
+	err = bc.request(ctx, "getMarketDepth2", []interface{}{limit}, &response)
 	orderbook = &s.Orderbook{response.MarketDepth.Ask, response.MarketDepth.Bid}
 	return
 }
 
-func (bc *BTCChina) History(_ s.Pair, since int64) (trades []s.Trade, next int64, err error) {
+func (bc *BTCChina) History(pair s.Pair, since int64) ([]s.Trade, int64, error) {
+	return bc.HistoryContext(context.Background(), pair, since)
+}
+
+func (bc *BTCChina) HistoryContext(ctx context.Context, _ s.Pair, since int64) (trades []s.Trade, next int64, err error) {
 	next = since
-	url := HISTORY
+	url := bc.endpoints.History
 	if since >= 0 {
 		url = fmt.Sprintf("%s?since=%d", url, since)
 	}
@@ -161,9 +533,9 @@ func (bc *BTCChina) History(_ s.Pair, since int64) (trades []s.Trade, next int64
 	var ts []struct {
 		Tid, Date     string
 		Type          s.TradeType
-		Amount, Price float64
+		Amount, Price s.Decimal
 	}
-	if err = getjson(bc.client, url, &ts); err != nil {
+	if err = getjson(ctx, bc.client, url, &ts); err != nil {
 		return
 	}
 
@@ -181,30 +553,70 @@ func (bc *BTCChina) History(_ s.Pair, since int64) (trades []s.Trade, next int64
 	return
 }
 
-func (bc *BTCChina) Ticker(_ s.Pair) (t *s.Ticker, err error) {
+func (bc *BTCChina) Ticker(pair s.Pair) (*s.Ticker, error) {
+	return bc.TickerContext(context.Background(), pair)
+}
+
+func (bc *BTCChina) TickerContext(ctx context.Context, _ s.Pair) (t *s.Ticker, err error) {
 	var v map[string]struct {
-		Buy, Sell, Last, Vol, High, Low string
+		Buy, Sell, Last, Vol, High, Low s.Decimal
 	}
-	if err = getjson(bc.client, TICKER, &v); err != nil {
+	if err = getjson(ctx, bc.client, bc.endpoints.Ticker, &v); err != nil {
 		return
 	}
 	ticker := v["ticker"]
-	t = new(s.Ticker)
-	t.Buy, _ = strconv.ParseFloat(ticker.Buy, 64)
-	t.Sell, _ = strconv.ParseFloat(ticker.Sell, 64)
-	t.Last, _ = strconv.ParseFloat(ticker.Last, 64)
-	t.Volume, _ = strconv.ParseFloat(ticker.Vol, 64)
-	t.High, _ = strconv.ParseFloat(ticker.High, 64)
-	t.Low, _ = strconv.ParseFloat(ticker.Low, 64)
+	t = &s.Ticker{
+		Buy:    ticker.Buy,
+		Sell:   ticker.Sell,
+		Last:   ticker.Last,
+		Volume: ticker.Vol,
+		High:   ticker.High,
+		Low:    ticker.Low,
+	}
 	return
 }
 
+// Markets returns the markets of BTCChina. The API does not provide them,
+// so they are hard-coded.
+func (bc *BTCChina) Markets() ([]s.MarketInfo, error) {
+	return []s.MarketInfo{{
+		Pair:      s.BTC_CNY,
+		TickSize:  s.DecimalUnit(2),
+		LotSize:   s.DecimalUnit(4),
+		MinAmount: s.DecimalUnit(3),
+	}}, nil
+}
+
+// StreamOptions are the options used by Stream. Copy them to use
+// coincross.TailContext with other options, such as a Checkpoint.
+var StreamOptions = s.TailOptions{
+	PollOptions: s.PollOptions{Interval: time.Second},
+	Join:        s.JoinByID,
+}
+
 func (bc *BTCChina) Stream(pair s.Pair, since int64) *s.Streamer {
-	return s.Tail(bc, pair, since, time.Second)
+	return s.TailContext(context.Background(), bc, pair, since, StreamOptions)
 }
 
+var (
+	_ s.Client            = (*BTCChina)(nil)
+	_ s.ContextClient     = (*BTCChina)(nil)
+	_ s.MarketLister      = (*BTCChina)(nil)
+	_ s.OrderGetter       = (*BTCChina)(nil)
+	_ s.OrderPlacer       = (*BTCChina)(nil)
+	_ s.FillLister        = (*BTCChina)(nil)
+	_ s.ContextFillLister = (*BTCChina)(nil)
+)
+
 func init() {
-	s.Register("btcchina", func(apikey, secret string, transport *http.Transport) s.Client {
-		return New(apikey, secret, transport)
+	s.RegisterExchange(s.Exchange{
+		Name:         exchange,
+		DisplayName:  "BTCChina",
+		Pairs:        []s.Pair{s.BTC_CNY},
+		Capabilities: []s.Capability{s.CapContext, s.CapMarkets, s.CapGetOrder, s.CapPlaceOrder, s.CapFills},
+		RateLimits:   DefaultRateLimits,
+		New: func(config *s.Config) (s.Client, error) {
+			return NewWithConfig(config)
+		},
 	})
 }
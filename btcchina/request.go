@@ -2,20 +2,34 @@ package btcchina
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
-	"time"
+
+	s "github.com/thinxer/coincross"
 )
 
-func (bc *BTCChina) request(method string, params []interface{}, reply interface{}) (err error) {
-	tonce := time.Now().UnixNano() / 1000
+func (bc *BTCChina) request(ctx context.Context, method string, params []interface{}, reply interface{}) (err error) {
+	// The tonce is taken after waiting, and the requests of the key are made
+	// one at a time, as the requests must arrive in order.
+	if ctx, err = bc.limiter.Allow(ctx, s.Private); err != nil {
+		return
+	}
+	lock := s.KeyLock(exchange, bc.apikey)
+	if err = lock.Lock(ctx); err != nil {
+		return
+	}
+	defer lock.Unlock()
+	tonce, err := bc.tonce.Nonce(bc.apikey)
+	if err != nil {
+		return
+	}
 	data := map[string]interface{}{
 		"id":            fmt.Sprintf("%d", tonce),
 		"tonce":         tonce,
@@ -36,40 +50,90 @@ func (bc *BTCChina) request(method string, params []interface{}, reply interface
 	digest := hex.EncodeToString(h.Sum(nil))
 
 	data_json, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", ENDPOINT, bytes.NewReader(data_json))
+	req, _ := http.NewRequestWithContext(ctx, "POST", bc.endpoints.Trade, bytes.NewReader(data_json))
 	req.SetBasicAuth(bc.apikey, digest)
 	req.Header.Set("Json-Rpc-Tonce", fmt.Sprintf("%d", tonce))
 	r, err := bc.client.Do(req)
 	if err == nil {
 		var response struct {
 			Result interface{}
-			Id     string
+			Error  *struct {
+				Code    int
+				Message string
+			}
+			Id string
 		}
 		response.Result = reply
-		return decode(r.Body, &response)
+		err = decode(r, method, &response)
+		// The error object tells more than the status, which may not be 2xx.
+		if response.Error != nil {
+			return rpcError(method, response.Error.Code, response.Error.Message)
+		}
 	}
 	return
 }
 
-func getjson(client *http.Client, url string, v interface{}) (err error) {
-	res, err := client.Get(url)
+// The known error codes of BTCChina.
+var errorCodes = map[int]s.ErrorCategory{
+	-32000: s.CategoryTemporary,         // Internal error
+	-32003: s.CategoryInsufficientFunds, // Insufficient CNY balance
+	-32004: s.CategoryInsufficientFunds, // Insufficient BTC balance
+	-32005: s.CategoryOrderNotFound,     // Order not found
+	-32006: s.CategoryAuth,              // Invalid user
+	-32007: s.CategoryInvalidOrder,      // Invalid currency
+	-32008: s.CategoryInvalidOrder,      // Invalid amount
+	-32010: s.CategoryTemporary,         // Under maintenance
+	-32017: s.CategoryInvalidOrder,      // Invalid type
+	-32018: s.CategoryInvalidOrder,      // Invalid price
+	-32019: s.CategoryInvalidOrder,      // Invalid parameter
+	-32025: s.CategoryOrderNotFound,     // Order already cancelled
+	-32026: s.CategoryOrderNotFound,     // Order already completed
+}
+
+// rpcError converts a JSON-RPC error reply to an ExchangeError.
+func rpcError(method string, code int, message string) error {
+	category, ok := errorCodes[code]
+	if !ok && strings.Contains(strings.ToLower(message), "tonce") {
+		category = s.CategoryNonce
+	}
+	return &s.ExchangeError{
+		Exchange: exchange,
+		Method:   method,
+		Code:     code,
+		Message:  message,
+		Category: category,
+	}
+}
+
+func getjson(ctx context.Context, client *http.Client, url string, v interface{}) (err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return
+	}
+	res, err := client.Do(req)
 	if err == nil {
-		return decode(res.Body, v)
+		return decode(res, req.URL.Path, v)
 	}
 	return
 }
 
-func decode(body io.ReadCloser, v interface{}) error {
-	content, err := ioutil.ReadAll(body)
-	body.Close()
+// decode decodes the reply into v. The body is decoded even if the status
+// is not 2xx, as it may hold an error object, but the status error is
+// returned then.
+func decode(res *http.Response, method string, v interface{}) error {
+	content, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
 	if err != nil {
 		return err
 	}
-	err = json.Unmarshal(content, v)
-	if err != nil {
+	statusErr := s.StatusError(exchange, method, res.StatusCode)
+	if err = json.Unmarshal(content, v); err != nil {
+		if statusErr != nil {
+			return statusErr
+		}
 		return fmt.Errorf("Unmarshal failed: %s", string(content))
 	}
-	return nil
+	return statusErr
 }
 
 func php_float(v interface{}) string {
@@ -83,6 +147,8 @@ func php_implode(values []interface{}) string {
 	parts := make([]string, 0)
 	for _, v := range values {
 		switch v := v.(type) {
+		case nil:
+			parts = append(parts, "")
 		case bool:
 			if v {
 				parts = append(parts, "1")
@@ -91,6 +157,8 @@ func php_implode(values []interface{}) string {
 			}
 		case float32, float64:
 			parts = append(parts, php_float(v))
+		case s.Decimal:
+			parts = append(parts, v.String())
 		case string:
 			parts = append(parts, v)
 		default:
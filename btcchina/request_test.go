@@ -0,0 +1,108 @@
+package btcchina
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	s "github.com/thinxer/coincross"
+)
+
+// newTestClient returns a client of a server answering every request with
+// status and body.
+func newTestClient(t *testing.T, status int, body string) *BTCChina {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	bc, err := NewWithConfig(&s.Config{
+		APIKey:      "key",
+		Secret:      "secret",
+		BaseURL:     srv.URL,
+		RateLimiter: s.NewRateLimiter(s.RateLimits{}, s.Block),
+		NonceSource: s.NewNonceCounter(s.UnixMicros),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bc
+}
+
+func TestRequestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		target error
+		code   int
+	}{
+		{"insufficient cny", 200,
+			`{"error":{"code":-32003,"message":"Insufficient CNY balance"},"id":"1"}`,
+			s.ErrInsufficientBalance, -32003},
+		{"order not found", 200,
+			`{"error":{"code":-32005,"message":"Order not found"},"id":"1"}`,
+			s.ErrOrderNotFound, -32005},
+		{"maintenance", 200,
+			`{"error":{"code":-32010,"message":"Under maintenance"},"id":"1"}`,
+			s.ErrTemporary, -32010},
+		// The error object is kept when the status is not 2xx.
+		{"tonce", 401,
+			`{"error":{"code":-32099,"message":"Tonce is out of sync."},"id":"1"}`,
+			s.ErrInvalidNonce, -32099},
+		{"bad gateway", 502,
+			`<html><body>502 Bad Gateway</body></html>`,
+			s.ErrTemporary, 502},
+	}
+	for _, test := range tests {
+		bc := newTestClient(t, test.status, test.body)
+		var reply interface{}
+		err := bc.request(context.Background(), "buyOrder2", []interface{}{"1", "1"}, &reply)
+		if !errors.Is(err, test.target) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.target)
+			continue
+		}
+		var e *s.ExchangeError
+		if !errors.As(err, &e) || e.Code != test.code {
+			t.Errorf("%s: got %v, want code %d", test.name, err, test.code)
+		}
+	}
+}
+
+func TestCancelNotFound(t *testing.T) {
+	bc := newTestClient(t, 200, `{"error":{"code":-32005,"message":"Order not found"},"id":"1"}`)
+	ok, err := bc.Cancel(1)
+	if ok || !errors.Is(err, s.ErrOrderNotFound) {
+		t.Errorf("got %v, %v, want ErrOrderNotFound", ok, err)
+	}
+}
+
+func TestRequestResult(t *testing.T) {
+	bc := newTestClient(t, 200, `{"result":true,"id":"1"}`)
+	ok, err := bc.Cancel(1)
+	if !ok || err != nil {
+		t.Errorf("got %v, %v, want true", ok, err)
+	}
+}
+
+// The requests of a key wait for each other, but not past their context.
+func TestRequestWaitsForKey(t *testing.T) {
+	bc := newTestClient(t, 200, `{"result":true,"id":"1"}`)
+	lock := s.KeyLock(exchange, bc.apikey)
+	if err := lock.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var ok bool
+	if err := bc.request(ctx, "cancelOrder", []interface{}{1}, &ok); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	lock.Unlock()
+	if err := bc.request(context.Background(), "cancelOrder", []interface{}{1}, &ok); err != nil || !ok {
+		t.Errorf("got %v, %v, want true", ok, err)
+	}
+}
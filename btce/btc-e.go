@@ -3,15 +3,17 @@ package btce
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,26 +21,140 @@ import (
 	s "github.com/thinxer/coincross"
 )
 
+// The name registered in coincross.
+const exchange = "btce"
+
 const (
 	PRIVATE_API = "https://btc-e.com/tapi"
 	PUBLIC_API  = "https://btc-e.com/api"
 )
 
+// Endpoints are the URLs of the BTC-E APIs.
+type Endpoints struct {
+	Private, Public string
+}
+
+// DefaultEndpoints are the official endpoints.
+var DefaultEndpoints = Endpoints{Private: PRIVATE_API, Public: PUBLIC_API}
+
+// Rebase returns the endpoints with the scheme and host replaced by base.
+func (e Endpoints) Rebase(base string) Endpoints {
+	return Endpoints{
+		Private: s.RebaseURL(e.Private, base),
+		Public:  s.RebaseURL(e.Public, base),
+	}
+}
+
+// Set replaces the endpoint of the name, either "private" or "public".
+func (e *Endpoints) Set(name, url string) error {
+	switch name {
+	case "private":
+		e.Private = url
+	case "public":
+		e.Public = url
+	default:
+		return fmt.Errorf("%s: unknown endpoint %q", exchange, name)
+	}
+	return nil
+}
+
+// DefaultRateLimits are kept well below the limits of BTC-E,
+// which bans the IPs going over them.
+var DefaultRateLimits = s.RateLimits{
+	Public:  s.Limit{Rate: 2, Burst: 5},
+	Private: s.Limit{Rate: 1, Burst: 3},
+}
+
 type BTCE struct {
 	key    string
 	secret []byte
 	client *http.Client
+	// The transport under the rate limiter.
+	base      http.RoundTripper
+	endpoints Endpoints
+	nonce     s.NonceSource
+	limiter   *s.RateLimiter
 }
 
+// BTC-E accepts nonces up to 4294967294, so seconds are used.
+var defaultNonce = s.NewNonceCounter(s.UnixSeconds)
+
+// New creates a client. A nil transport means http.DefaultTransport.
 func New(apikey, secret string, transport *http.Transport) *BTCE {
-	return &BTCE{apikey, []byte(secret), &http.Client{
-		Transport: transport,
-	}}
+	config := &s.Config{APIKey: apikey, Secret: secret}
+	if transport != nil {
+		config.Transport = transport
+	}
+	b, _ := NewWithConfig(config)
+	return b
 }
 
-func (b *BTCE) request(method string, params map[string]interface{}, v interface{}) (err error) {
+// NewWithConfig creates a client with all the options of coincross.Config.
+// The endpoints in config.Endpoints are named as in Endpoints.Set.
+func NewWithConfig(config *s.Config) (*BTCE, error) {
+	b := &BTCE{
+		key:       config.APIKey,
+		secret:    []byte(config.Secret),
+		client:    new(http.Client),
+		base:      config.Transport,
+		endpoints: DefaultEndpoints.Rebase(config.BaseURL),
+		nonce:     defaultNonce,
+	}
+	for name, url := range config.Endpoints {
+		if err := b.endpoints.Set(name, url); err != nil {
+			return nil, err
+		}
+	}
+	if config.Logger != nil {
+		b.base = s.LogTransport(b.base, config.Logger)
+	}
+	if config.NonceSource != nil {
+		b.nonce = config.NonceSource
+	}
+	limiter := config.RateLimiter
+	if limiter == nil {
+		limiter = s.SharedRateLimiter(exchange, config.APIKey, DefaultRateLimits, s.Block)
+	}
+	b.SetRateLimiter(limiter)
+	return b, nil
+}
+
+// SetRateLimiter replaces the rate limiter. By default, the clients of the
+// same key share a limiter with DefaultRateLimits in Block mode.
+func (b *BTCE) SetRateLimiter(limiter *s.RateLimiter) {
+	b.limiter = limiter
+	b.client.Transport = s.RateLimitTransport(b.base, limiter)
+}
+
+// SetNonceSource replaces the nonce source, which is shared by all the
+// clients in the process by default. Use a FileNonce if the same key is used
+// by multiple processes.
+func (b *BTCE) SetNonceSource(nonce s.NonceSource) {
+	b.nonce = nonce
+}
+
+// SetEndpoints replaces the endpoints, which are DefaultEndpoints by default.
+func (b *BTCE) SetEndpoints(endpoints Endpoints) {
+	b.endpoints = endpoints
+}
+
+func (b *BTCE) request(ctx context.Context, method string, params map[string]interface{}, v interface{}) (err error) {
+	// The nonce is taken after waiting, and the requests of the key are made
+	// one at a time, as BTC-E rejects the nonces arriving out of order.
+	if ctx, err = b.limiter.Allow(ctx, s.Private); err != nil {
+		return
+	}
+	lock := s.KeyLock(exchange, b.key)
+	if err = lock.Lock(ctx); err != nil {
+		return
+	}
+	defer lock.Unlock()
+	nonce, err := b.nonce.Nonce(b.key)
+	if err != nil {
+		return
+	}
 	params["method"] = method
-	params["nonce"] = time.Now().Unix()
+	params["nonce"] = nonce
 	form := url.Values{}
 	for key, value := range params {
 		form.Set(key, fmt.Sprintf("%v", value))
@@ -49,7 +165,7 @@ func (b *BTCE) request(method string, params map[string]interface{}, v interface
 	h.Write(data)
 	sign := hex.EncodeToString(h.Sum(nil))
 
-	request, _ := http.NewRequest("POST", PRIVATE_API, bytes.NewReader(data))
+	request, _ := http.NewRequestWithContext(ctx, "POST", b.endpoints.Private, bytes.NewReader(data))
 	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	request.Header.Set("Key", b.key)
 	request.Header.Set("Sign", sign)
@@ -61,17 +177,69 @@ func (b *BTCE) request(method string, params map[string]interface{}, v interface
 			Error   string
 		}
 		body.Return = v
-		err = decode(response.Body, &body)
+		err = decode(response, method, &body)
 		if err == nil {
 			if body.Success == 0 {
-				return fmt.Errorf("BTC-E Error: %v", body.Error)
+				return &s.ExchangeError{
+					Exchange: exchange,
+					Method:   method,
+					Message:  body.Error,
+					Category: classify(body.Error),
+				}
 			}
 		}
 	}
 	return
 }
 
-type Funds map[string]float64
+// BTC-E reports errors in messages only. These are the known ones.
+var errorMessages = []struct {
+	substr   string
+	category s.ErrorCategory
+}{
+	{"invalid api key", s.CategoryAuth},
+	{"invalid sign", s.CategoryAuth},
+	{"permission", s.CategoryPermission},
+	{"not enough", s.CategoryInsufficientFunds},
+	{"nonce", s.CategoryNonce},
+	{"too often", s.CategoryRateLimited},
+	{"too many requests", s.CategoryRateLimited},
+	{"bad status", s.CategoryOrderNotFound},
+	{"invalid order", s.CategoryOrderNotFound},
+	{"order not found", s.CategoryOrderNotFound},
+	{"must be greater", s.CategoryInvalidOrder},
+	{"must be less", s.CategoryInvalidOrder},
+	{"invalid pair", s.CategoryInvalidOrder},
+	{"invalid parameter", s.CategoryInvalidOrder},
+	{"incorrectly entered", s.CategoryInvalidOrder},
+	{"busy", s.CategoryTemporary},
+	{"maintenance", s.CategoryTemporary},
+}
+
+// The errors BTC-E returns for empty lists.
+var emptyMessages = map[string]bool{
+	"no orders": true,
+	"no trades": true,
+}
+
+// isEmpty tells whether err is the error BTC-E returns for an empty list.
+func isEmpty(err error) bool {
+	var e *s.ExchangeError
+	return errors.As(err, &e) && emptyMessages[strings.ToLower(strings.TrimSpace(e.Message))]
+}
+
+// classify returns the category of a BTC-E error message.
+func classify(message string) s.ErrorCategory {
+	message = strings.ToLower(message)
+	for _, m := range errorMessages {
+		if strings.Contains(message, m.substr) {
+			return m.category
+		}
+	}
+	return s.CategoryUnknown
+}
+
+type Funds map[string]s.Decimal
 type AccountInfo struct {
 	Funds  Funds
 	Rights struct {
@@ -85,14 +253,23 @@ type AccountInfo struct {
 }
 
 func (b *BTCE) AccountInfo() (info *AccountInfo, err error) {
+	return b.accountInfo(context.Background())
+}
+
+func (b *BTCE) accountInfo(ctx context.Context) (info *AccountInfo, err error) {
 	info = new(AccountInfo)
-	err = b.request("getInfo", map[string]interface{}{}, info)
+	err = b.request(ctx, "getInfo", map[string]interface{}{}, info)
 	return
 }
 
-func (b *BTCE) Balance() (balance map[s.Symbol]float64, err error) {
-	if info, err := b.AccountInfo(); err == nil {
-		balance = make(map[s.Symbol]float64)
+func (b *BTCE) Balance() (map[s.Symbol]s.Decimal, error) {
+	return b.BalanceContext(context.Background())
+}
+
+func (b *BTCE) BalanceContext(ctx context.Context) (balance map[s.Symbol]s.Decimal, err error) {
+	info, err := b.accountInfo(ctx)
+	if err == nil {
+		balance = make(map[s.Symbol]s.Decimal)
 		for symbol, amount := range info.Funds {
 			balance[s.Symbol(strings.ToUpper(symbol))] = amount
 		}
@@ -100,131 +277,291 @@ func (b *BTCE) Balance() (balance map[s.Symbol]float64, err error) {
 	return
 }
 
-func (b *BTCE) Trade(tradeType s.TradeType, pair s.Pair, price, amount float64) (orderId int64, err error) {
-	var reply struct {
-		Received float64
-		Remains  float64
-		OrderId  int64 `json:"order_id"`
-		Funds    Funds
+func (b *BTCE) Trade(tradeType s.TradeType, pair s.Pair, price, amount s.Decimal) (int64, error) {
+	return b.TradeContext(context.Background(), tradeType, pair, price, amount)
+}
+
+func (b *BTCE) TradeContext(ctx context.Context, tradeType s.TradeType, pair s.Pair, price, amount s.Decimal) (orderId int64, err error) {
+	reply, err := b.trade(ctx, tradeType, pair, price, amount)
+	if err == nil {
+		orderId = reply.OrderId
 	}
-	err = b.request("Trade", map[string]interface{}{
+	return
+}
+
+type tradeReply struct {
+	Received s.Decimal
+	Remains  s.Decimal
+	// 0 if the order is filled at once.
+	OrderId int64 `json:"order_id"`
+	Funds   Funds
+}
+
+func (b *BTCE) trade(ctx context.Context, tradeType s.TradeType, pair s.Pair, price, amount s.Decimal) (reply tradeReply, err error) {
+	err = b.request(ctx, "Trade", map[string]interface{}{
 		"pair":   pair.LowerString(),
 		"type":   strings.ToLower(tradeType.String()),
 		"rate":   price,
 		"amount": amount,
 	}, &reply)
-	if err == nil {
-		orderId = reply.OrderId
+	return
+}
+
+// PlaceOrder places a limit order. IOC is emulated by cancelling the
+// remainder. Market, FOK and post-only orders are not supported.
+func (b *BTCE) PlaceOrder(req s.OrderRequest) (s.Order, error) {
+	return b.PlaceOrderContext(context.Background(), req)
+}
+
+func (b *BTCE) PlaceOrderContext(ctx context.Context, req s.OrderRequest) (order s.Order, err error) {
+	if err = req.Check(); err != nil {
+		return
+	}
+	if req.Type != s.LimitOrder {
+		return order, s.UnsupportedOrder(exchange, "%s orders not supported", req.Type)
+	}
+	if req.TimeInForce != s.GTC && req.TimeInForce != s.IOC {
+		return order, s.UnsupportedOrder(exchange, "%s orders not supported", req.TimeInForce)
+	}
+	reply, err := b.trade(ctx, req.Side, req.Pair, req.Price, req.Amount)
+	if err != nil {
+		return
+	}
+	order = req.Order(reply.OrderId)
+	order.Remain = reply.Remains
+	switch {
+	case reply.OrderId == 0:
+		order.Remain, order.Status = 0, s.StatusFilled
+	case order.Remain < order.Amount:
+		order.Status = s.StatusPartial
+	}
+	if req.TimeInForce == s.IOC {
+		return s.CancelRemainder(b, order)
 	}
 	return
 }
 
-func (b *BTCE) Cancel(orderId int64) (success bool, err error) {
+func (b *BTCE) Cancel(orderId int64) (bool, error) {
+	return b.CancelContext(context.Background(), orderId)
+}
+
+func (b *BTCE) CancelContext(ctx context.Context, orderId int64) (success bool, err error) {
 	var reply struct {
 		OrderId int64 `json:"order_id"`
 		Funds   Funds
 	}
-	err = b.request("CancelOrder", map[string]interface{}{"order_id": orderId}, &reply)
+	err = b.request(ctx, "CancelOrder", map[string]interface{}{"order_id": orderId}, &reply)
 	success = reply.OrderId == orderId
 	return
 }
 
-// Transactions returns your transactions,
+// Transactions returns your transactions, the latest first,
 // including trades, deposits, withdraws, placed/cancelled orders etc.
-func (b *BTCE) Transactions(limit int) (transactions []s.Transaction, err error) {
-	var reply map[string]struct {
-		Type      int
-		Amount    float64
-		Currency  string
-		Desc      string
-		Status    int
-		Timestamp int64
-	}
-	if err = b.request("TransHistory", map[string]interface{}{"count": limit, "order": "DESC"}, &reply); err != nil {
+// The descriptions are parsed to fill the Kind and Amounts.
+func (b *BTCE) Transactions(limit int) ([]s.Transaction, error) {
+	return b.TransactionsContext(context.Background(), limit)
+}
+
+func (b *BTCE) TransactionsContext(ctx context.Context, limit int) (transactions []s.Transaction, err error) {
+	var reply map[string]transEntry
+	if err = b.request(ctx, "TransHistory", map[string]interface{}{"count": limit, "order": "DESC"}, &reply); err != nil {
 		return
 	}
-	for id, tr := range reply {
-		var t s.Transaction
-		t.Id, _ = strconv.ParseInt(id, 10, 64)
-		t.Timestamp = tr.Timestamp
-		// TODO parse DESC and fill amounts better
-		t.Amounts = map[s.Symbol]float64{
-			s.Symbol(strings.ToUpper(tr.Currency)): tr.Amount,
-		}
-		t.Descritpion = tr.Desc
-		transactions = append(transactions, t)
+	entries := make([]transEntry, 0, len(reply))
+	for id, e := range reply {
+		e.Id, _ = strconv.ParseInt(id, 10, 64)
+		entries = append(entries, e)
 	}
-	return
+	return parseTransactions(entries), nil
 }
 
 // Orders will return your active orders for all pairs.
-func (b *BTCE) Orders() (orders []s.Order, err error) {
-	var reply map[string]struct {
-		Pair             s.Pair
-		Type             s.TradeType
-		Amount           float64
-		Rate             float64
-		TimestampCreated int64 `json:"timestamp_created"`
-		Status           int
-	}
-	err = b.request("ActiveOrders", map[string]interface{}{}, &reply)
+func (b *BTCE) Orders() ([]s.Order, error) {
+	return b.OrdersContext(context.Background())
+}
+
+func (b *BTCE) OrdersContext(ctx context.Context) (orders []s.Order, err error) {
+	var reply map[string]orderInfo
+	if err = b.request(ctx, "ActiveOrders", map[string]interface{}{}, &reply); isEmpty(err) {
+		return nil, nil
+	}
 	for id, order := range reply {
-		var o s.Order
-		o.Id, _ = strconv.ParseInt(id, 10, 64)
-		o.Pair = order.Pair
-		o.Type = order.Type
-		o.Price = order.Rate
-		o.Remain = order.Amount
-		o.Amount = order.Amount
-		o.Timestamp = order.TimestampCreated
-		orders = append(orders, o)
+		orders = append(orders, order.order(id))
+	}
+	return
+}
+
+// GetOrder returns the order of the id, including the closed ones.
+func (b *BTCE) GetOrder(orderId int64) (s.Order, error) {
+	return b.GetOrderContext(context.Background(), orderId)
+}
+
+func (b *BTCE) GetOrderContext(ctx context.Context, orderId int64) (order s.Order, err error) {
+	var reply map[string]orderInfo
+	if err = b.request(ctx, "OrderInfo", map[string]interface{}{"order_id": orderId}, &reply); err != nil {
+		return
+	}
+	for id, info := range reply {
+		return info.order(id), nil
+	}
+	return order, &s.ExchangeError{
+		Exchange: exchange,
+		Method:   "OrderInfo",
+		Message:  "order not found",
+		Category: s.CategoryOrderNotFound,
+	}
+}
+
+// orderInfo is an order in the replies of ActiveOrders and OrderInfo.
+type orderInfo struct {
+	Pair s.Pair
+	Type s.TradeType
+	// Only in OrderInfo.
+	StartAmount      s.Decimal `json:"start_amount"`
+	Amount           s.Decimal
+	Rate             s.Decimal
+	TimestampCreated int64 `json:"timestamp_created"`
+	Status           int
+}
+
+func (info orderInfo) order(id string) (o s.Order) {
+	o.Id, _ = strconv.ParseInt(id, 10, 64)
+	o.Pair = info.Pair
+	o.Type = info.Type
+	o.Price = info.Rate
+	o.Remain = info.Amount
+	o.Amount = info.StartAmount
+	if o.Amount == 0 {
+		o.Amount = info.Amount
+	}
+	o.Timestamp = info.TimestampCreated
+	switch info.Status {
+	case 0:
+		o.Status = s.StatusOpen
+		if o.Remain < o.Amount {
+			o.Status = s.StatusPartial
+		}
+	case 1:
+		o.Status = s.StatusFilled
+	case 2, 3:
+		// 3 is cancelled after partial fills.
+		o.Status = s.StatusCancelled
 	}
 	return
 }
 
 // TradeHistory returns your past trade transactions.
 func (b *BTCE) TradeHistory(pair s.Pair, since int64) (trades []s.Trade, err error) {
-	var reply map[string]struct {
-		Pair        s.Pair
-		Type        s.TradeType
-		Amount      float64
-		Rate        float64
-		OrderId     int64 `json:"order_id"`
-		IsYourOrder int   `json:"is_your_order"`
-		Timestamp   int64
-	}
-	params := map[string]interface{}{"from_id": since, "order": "DESC"}
+	fills, err := b.tradeHistory(context.Background(), pair, map[string]interface{}{"from_id": since, "order": "DESC"})
+	for _, f := range fills {
+		trades = append(trades, s.Trade{
+			Id:        f.TradeId,
+			Timestamp: f.Timestamp,
+			Type:      f.Side,
+			Price:     f.Price,
+			Amount:    f.Amount,
+			Pair:      f.Pair,
+		})
+	}
+	return
+}
+
+// Fills returns up to 1000 of your trades since the time. BTC-E does not
+// report the fees of the trades, so they are computed from the fees of the
+// pairs in Info, which are charged on every trade in the currency received.
+// They are left unknown if Info fails. The OrderId of a fill is only known
+// if it was made against our order on the book.
+func (b *BTCE) Fills(pair s.Pair, since int64) ([]s.Fill, error) {
+	return b.FillsContext(context.Background(), pair, since)
+}
+
+func (b *BTCE) FillsContext(ctx context.Context, pair s.Pair, since int64) (fills []s.Fill, err error) {
+	if fills, err = b.tradeHistory(ctx, pair, map[string]interface{}{"since": since, "order": "ASC"}); err != nil {
+		return
+	}
+	sort.Slice(fills, func(i, j int) bool { return fills[i].TradeId < fills[j].TradeId })
+	info, ierr := b.info(ctx)
+	if ierr != nil {
+		return
+	}
+	for i := range fills {
+		f := &fills[i]
+		p, ok := info.Pairs[f.Pair.LowerString()]
+		if !ok {
+			continue
+		}
+		// The fee is given in percents.
+		rate := p.Fee.Div(s.NewDecimal(100))
+		if f.Side == s.Buy {
+			f.Fee, f.FeeSymbol = f.Amount.Mul(rate), f.Pair.Target
+		} else {
+			f.Fee, f.FeeSymbol = f.Amount.Mul(f.Price).Mul(rate), f.Pair.Base
+		}
+	}
+	return
+}
+
+// tradeEntry is an entry of TradeHistory.
+type tradeEntry struct {
+	Pair        s.Pair
+	Type        s.TradeType
+	Amount      s.Decimal
+	Rate        s.Decimal
+	OrderId     int64 `json:"order_id"`
+	IsYourOrder int   `json:"is_your_order"`
+	Timestamp   int64
+}
+
+// fill converts the trade of the id. The order of the trade is the one on
+// the book, which is ours only if IsYourOrder is set.
+func (trade tradeEntry) fill(id string) (f s.Fill) {
+	f.TradeId, _ = strconv.ParseInt(id, 10, 64)
+	f.Side = trade.Type
+	f.Pair = trade.Pair
+	f.Price = trade.Rate
+	f.Amount = trade.Amount
+	f.Timestamp = trade.Timestamp
+	f.Liquidity = s.Taker
+	if trade.IsYourOrder != 0 {
+		// The trade was made against our order on the book.
+		f.OrderId, f.Liquidity = trade.OrderId, s.Maker
+	}
+	return
+}
+
+func (b *BTCE) tradeHistory(ctx context.Context, pair s.Pair, params map[string]interface{}) (fills []s.Fill, err error) {
+	var reply map[string]tradeEntry
 	if pair != s.ALL {
 		params["pair"] = pair.LowerString()
 	}
-	if err = b.request("TradeHistory", params, &reply); err != nil {
+	if err = b.request(ctx, "TradeHistory", params, &reply); err != nil {
+		if isEmpty(err) {
+			err = nil
+		}
 		return
 	}
 	for id, trade := range reply {
-		var t s.Trade
-		t.Id, _ = strconv.ParseInt(id, 10, 64)
-		t.Price = trade.Rate
-		t.Amount = trade.Amount
-		t.Timestamp = trade.Timestamp
-		t.Type = trade.Type
-		t.Pair = trade.Pair
-		trades = append(trades, t)
+		fills = append(fills, trade.fill(id))
 	}
 	return
 }
 
-func (b *BTCE) Orderbook(pair s.Pair, limit int) (orderbook *s.Orderbook, err error) {
-	url := fmt.Sprintf("%s/3/depth/%s", PUBLIC_API, pair.LowerString())
+func (b *BTCE) Orderbook(pair s.Pair, limit int) (*s.Orderbook, error) {
+	return b.OrderbookContext(context.Background(), pair, limit)
+}
+
+func (b *BTCE) OrderbookContext(ctx context.Context, pair s.Pair, limit int) (orderbook *s.Orderbook, err error) {
+	url := fmt.Sprintf("%s/3/depth/%s", b.endpoints.Public, pair.LowerString())
 	var reply map[string]struct {
-		Asks, Bids [][]float64
+		Asks, Bids [][]s.Decimal
 	}
-	transform := func(trades [][]float64) (r []struct{ Price, Amount float64 }) {
+	transform := func(trades [][]s.Decimal) (r []struct{ Price, Amount s.Decimal }) {
 		for _, p := range trades {
-			r = append(r, struct{ Price, Amount float64 }{p[0], p[1]})
+			r = append(r, struct{ Price, Amount s.Decimal }{p[0], p[1]})
 		}
 		return
 	}
-	if err = getjson(b.client, url, &reply); err != nil {
+	if err = getjson(ctx, b.client, url, &reply); err != nil {
 		return
 	}
 	orderbook = new(s.Orderbook)
@@ -235,20 +572,24 @@ func (b *BTCE) Orderbook(pair s.Pair, limit int) (orderbook *s.Orderbook, err er
 }
 
 // Note that BTC-E use `Timestamp` field for the `since` parameter
-func (b *BTCE) History(pair s.Pair, since int64) (trades []s.Trade, next int64, err error) {
+func (b *BTCE) History(pair s.Pair, since int64) ([]s.Trade, int64, error) {
+	return b.HistoryContext(context.Background(), pair, since)
+}
+
+func (b *BTCE) HistoryContext(ctx context.Context, pair s.Pair, since int64) (trades []s.Trade, next int64, err error) {
 	next = since
-	url := fmt.Sprintf("%s/3/trades/%s", PUBLIC_API, pair.LowerString())
+	url := fmt.Sprintf("%s/3/trades/%s", b.endpoints.Public, pair.LowerString())
 	if since > 0 {
 		url = fmt.Sprintf("%s?since=%d", url, since)
 	}
 	var reply map[string][]struct {
 		Tid       int64
-		Price     float64
-		Amount    float64
+		Price     s.Decimal
+		Amount    s.Decimal
 		Type      s.TradeType
 		Timestamp int64
 	}
-	if err = getjson(b.client, url, &reply); err != nil {
+	if err = getjson(ctx, b.client, url, &reply); err != nil {
 		return
 	}
 	reply_trades := reply[pair.LowerString()]
@@ -267,14 +608,18 @@ func (b *BTCE) History(pair s.Pair, since int64) (trades []s.Trade, next int64,
 	return
 }
 
-func (b *BTCE) Ticker(pair s.Pair) (t *s.Ticker, err error) {
-	url := fmt.Sprintf("%s/3/ticker/%s", PUBLIC_API, pair.LowerString())
+func (b *BTCE) Ticker(pair s.Pair) (*s.Ticker, error) {
+	return b.TickerContext(context.Background(), pair)
+}
+
+func (b *BTCE) TickerContext(ctx context.Context, pair s.Pair) (t *s.Ticker, err error) {
+	url := fmt.Sprintf("%s/3/ticker/%s", b.endpoints.Public, pair.LowerString())
 	var reply map[string]struct {
-		High, Low, Avg, Vol, Last, Buy, Sell float64
-		Vol_Cur                              float64 `json:"vol_cur"`
+		High, Low, Avg, Vol, Last, Buy, Sell s.Decimal
+		Vol_Cur                              s.Decimal `json:"vol_cur"`
 		Updated                              int64
 	}
-	if err = getjson(b.client, url, &reply); err != nil {
+	if err = getjson(ctx, b.client, url, &reply); err != nil {
 		return
 	}
 	tt := reply[pair.LowerString()]
@@ -282,43 +627,97 @@ func (b *BTCE) Ticker(pair s.Pair) (t *s.Ticker, err error) {
 	return
 }
 
+// StreamOptions are the options used by Stream. Copy them to use
+// coincross.TailContext with other options, such as a Checkpoint.
+var StreamOptions = s.TailOptions{
+	PollOptions: s.PollOptions{Interval: time.Second * 2},
+	Join:        s.JoinByTimestamp,
+}
+
 func (b *BTCE) Stream(pair s.Pair, since int64) *s.Streamer {
-	return s.Tail(b, pair, since, time.Second*2)
+	return s.TailContext(context.Background(), b, pair, since, StreamOptions)
 }
 
 type Info struct {
 	ServerTime int64 `json:"server_time"`
 	Pairs      map[string]struct {
-		DecimalPlaces int     `json:"decimal_places"`
-		MinPrice      float64 `json:"min_price"`
-		MaxPrice      float64 `json:"max_price"`
-		MinAmount     float64 `json:"min_amount"`
+		DecimalPlaces int       `json:"decimal_places"`
+		MinPrice      s.Decimal `json:"min_price"`
+		MaxPrice      s.Decimal `json:"max_price"`
+		MinAmount     s.Decimal `json:"min_amount"`
 		Hidden        int
-		Fee           float64
+		Fee           s.Decimal
 	}
 }
 
 func (b *BTCE) Info() (info *Info, err error) {
-	url := fmt.Sprintf("%s/3/info", PUBLIC_API)
+	return b.info(context.Background())
+}
+
+func (b *BTCE) info(ctx context.Context) (info *Info, err error) {
+	url := fmt.Sprintf("%s/3/info", b.endpoints.Public)
 	info = new(Info)
-	err = getjson(b.client, url, info)
+	err = getjson(ctx, b.client, url, info)
+	return
+}
+
+// Markets returns the markets listed in Info, except the hidden ones.
+func (b *BTCE) Markets() (markets []s.MarketInfo, err error) {
+	info, err := b.Info()
+	if err != nil {
+		return
+	}
+	for name, p := range info.Pairs {
+		if p.Hidden != 0 {
+			continue
+		}
+		markets = append(markets, s.MarketInfo{
+			Pair:      parsePair(name),
+			TickSize:  s.DecimalUnit(p.DecimalPlaces),
+			LotSize:   s.DecimalUnit(s.DecimalPlaces),
+			MinPrice:  p.MinPrice,
+			MaxPrice:  p.MaxPrice,
+			MinAmount: p.MinAmount,
+			// The fee is given in percents.
+			Fee: p.Fee.Div(s.NewDecimal(100)),
+		})
+	}
+	sort.Slice(markets, func(i, j int) bool {
+		return markets[i].Pair.LowerString() < markets[j].Pair.LowerString()
+	})
 	return
 }
 
-func getjson(client *http.Client, url string, v interface{}) (err error) {
-	res, err := client.Get(url)
+// parsePair returns {USD, BTC} for "btc_usd".
+func parsePair(name string) s.Pair {
+	parts := strings.SplitN(strings.ToUpper(name), "_", 2)
+	if len(parts) < 2 {
+		return s.Pair{Target: s.Symbol(parts[0])}
+	}
+	return s.Pair{Base: s.Symbol(parts[1]), Target: s.Symbol(parts[0])}
+}
+
+func getjson(ctx context.Context, client *http.Client, url string, v interface{}) (err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return
+	}
+	res, err := client.Do(req)
 	if err == nil {
-		return decode(res.Body, v)
+		return decode(res, req.URL.Path, v)
 	}
 	return
 }
 
-func decode(body io.ReadCloser, v interface{}) error {
-	content, err := ioutil.ReadAll(body)
-	body.Close()
+func decode(res *http.Response, method string, v interface{}) error {
+	content, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
 	if err != nil {
 		return err
 	}
+	if err = s.StatusError(exchange, method, res.StatusCode); err != nil {
+		return err
+	}
 	err = json.Unmarshal(content, v)
 	if err != nil {
 		return fmt.Errorf("Unmarshal failed: %s", string(content))
@@ -326,8 +725,25 @@ func decode(body io.ReadCloser, v interface{}) error {
 	return nil
 }
 
+var (
+	_ s.Client            = (*BTCE)(nil)
+	_ s.ContextClient     = (*BTCE)(nil)
+	_ s.MarketLister      = (*BTCE)(nil)
+	_ s.FillLister        = (*BTCE)(nil)
+	_ s.ContextFillLister = (*BTCE)(nil)
+	_ s.OrderGetter       = (*BTCE)(nil)
+	_ s.OrderPlacer       = (*BTCE)(nil)
+)
+
 func init() {
-	s.Register("btce", func(apikey, secret string, transport *http.Transport) s.Client {
-		return New(apikey, secret, transport)
+	s.RegisterExchange(s.Exchange{
+		Name:         exchange,
+		DisplayName:  "BTC-E",
+		Pairs:        []s.Pair{s.BTC_USD, s.LTC_USD, s.LTC_BTC},
+		Capabilities: []s.Capability{s.CapContext, s.CapMarkets, s.CapGetOrder, s.CapPlaceOrder, s.CapFills},
+		RateLimits:   DefaultRateLimits,
+		New: func(config *s.Config) (s.Client, error) {
+			return NewWithConfig(config)
+		},
 	})
 }
@@ -0,0 +1,264 @@
+package btce
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	s "github.com/thinxer/coincross"
+)
+
+// transEntry is an entry of TransHistory.
+type transEntry struct {
+	Id        int64 `json:"-"`
+	Type      int
+	Amount    s.Decimal
+	Currency  string
+	Desc      string
+	Status    int
+	Timestamp int64
+}
+
+// The types of TransHistory.
+const (
+	transDeposit    = 1
+	transWithdrawal = 2
+	transCredit     = 4
+	transDebit      = 5
+)
+
+// signed returns the amount, negative for withdrawals and debits.
+func (e transEntry) signed() s.Decimal {
+	switch e.Type {
+	case transDeposit, transCredit:
+		return e.Amount.Abs()
+	case transWithdrawal, transDebit:
+		return e.Amount.Abs().Neg()
+	}
+	return e.Amount
+}
+
+// BTC-E records every transaction in a single currency, and tells the rest in
+// the description. These are the known descriptions, such as:
+//
+//	Buy 0.1 BTC (BTC/USD) at 800 USD
+//	Bought 0.1 BTC from your order :order:1234: by price 800 USD total 80 USD (-0.2%)
+//	Cancel order :order:1234:
+var (
+	tradeDesc      = regexp.MustCompile(`(?i)^(bought|sold)\s+([0-9.]+)\s+(\w+)\b`)
+	priceDesc      = regexp.MustCompile(`(?i)\bby price\s+([0-9.]+)\s+(\w+)`)
+	totalDesc      = regexp.MustCompile(`(?i)\btotal\s+([0-9.]+)\s+(\w+)`)
+	placedDesc     = regexp.MustCompile(`(?i)^(buy|sell)\s+([0-9.]+)\s+(\w+)\b.*\bat\s+([0-9.]+)\s+(\w+)`)
+	cancelDesc     = regexp.MustCompile(`(?i)^cancel(l?ed)?\s+order\b`)
+	orderDesc      = regexp.MustCompile(`:order:(\d+):`)
+	feeDesc        = regexp.MustCompile(`(?i)\bfee\b`)
+	withdrawalDesc = regexp.MustCompile(`(?i)\bwithdraw`)
+	depositDesc    = regexp.MustCompile(`(?i)\b(deposit|payment)\b`)
+)
+
+// placement is an order placed in the transactions, and the funds moved into
+// it which are not accounted for yet.
+type placement struct {
+	t           *s.Transaction
+	side        s.TradeType
+	target      s.Symbol
+	base        s.Symbol
+	price       s.Decimal
+	currency    s.Symbol
+	outstanding s.Decimal
+}
+
+// parseTransactions converts the entries of TransHistory, the latest first.
+//
+// BTC-E takes the funds of an order when it is placed, gives back the rest
+// when it is cancelled, and only records the currency received by a trade.
+// When the placement of an order is found, the amount paid by its trades is
+// put in the trades and taken off the placement, and so is the amount given
+// back by its cancellation, so that the placement of a finished order is
+// left empty, and the placement of an open order holds its funds. The
+// entries which are not understood are kept as they are, so the amounts
+// always add up as recorded.
+func parseTransactions(entries []transEntry) []s.Transaction {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Id < entries[j].Id })
+	transactions := make([]s.Transaction, len(entries))
+	p := &transParser{byOrder: make(map[int64]*placement)}
+	for i, e := range entries {
+		t := &transactions[i]
+		*t = s.Transaction{
+			Id:          e.Id,
+			Timestamp:   e.Timestamp,
+			Amounts:     map[s.Symbol]s.Decimal{s.Symbol(strings.ToUpper(e.Currency)): e.signed()},
+			Descritpion: e.Desc,
+		}
+		switch e.Type {
+		case transDeposit:
+			t.Kind = s.TransactionDeposit
+		case transWithdrawal:
+			t.Kind = s.TransactionWithdrawal
+		default:
+			p.parse(t, e)
+		}
+	}
+	for _, q := range p.byOrder {
+		q.settle()
+	}
+	for _, q := range p.pending {
+		q.settle()
+	}
+	for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+		transactions[i], transactions[j] = transactions[j], transactions[i]
+	}
+	return transactions
+}
+
+// transParser keeps the placements found so far.
+type transParser struct {
+	byOrder map[int64]*placement
+	// The placements of unknown orders, oldest first.
+	pending []*placement
+}
+
+// parse fills the Kind of t, and settles the trades and cancellations.
+func (p *transParser) parse(t *s.Transaction, e transEntry) {
+	currency, amount := s.Symbol(strings.ToUpper(e.Currency)), e.signed()
+	var orderId int64
+	if m := orderDesc.FindStringSubmatch(e.Desc); m != nil {
+		orderId, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+
+	if tr, ok := parseTrade(e.Desc); ok {
+		t.Kind = s.TransactionSell
+		if tr.side == s.Buy {
+			t.Kind = s.TransactionBuy
+		}
+		// The recorded amount is the one received, after the fee.
+		if currency != tr.received {
+			return
+		}
+		if q := p.find(orderId, tr); q != nil {
+			t.Amounts[q.currency] = tr.paid.Neg()
+			q.outstanding = q.outstanding.Add(tr.paid)
+		}
+		return
+	}
+	if cancelDesc.MatchString(e.Desc) {
+		t.Kind = s.TransactionOrderCancelled
+		if q := p.byOrder[orderId]; q != nil && orderId != 0 && q.currency == currency {
+			q.outstanding = q.outstanding.Add(amount)
+			t.Amounts = map[s.Symbol]s.Decimal{}
+		}
+		return
+	}
+	if q := parsePlacement(t, currency, amount, e.Desc); q != nil {
+		t.Kind = s.TransactionOrderPlaced
+		if orderId != 0 {
+			p.byOrder[orderId] = q
+		} else {
+			p.pending = append(p.pending, q)
+		}
+		return
+	}
+	switch {
+	case feeDesc.MatchString(e.Desc):
+		t.Kind = s.TransactionFee
+	case withdrawalDesc.MatchString(e.Desc):
+		t.Kind = s.TransactionWithdrawal
+	case depositDesc.MatchString(e.Desc):
+		t.Kind = s.TransactionDeposit
+	}
+}
+
+// find returns the placement of the order of a trade, or nil if unknown.
+// Without the order id in the placement, it is the oldest placement of the
+// same side, pair and price with enough funds left.
+func (p *transParser) find(orderId int64, tr trade) *placement {
+	if orderId == 0 {
+		return nil
+	}
+	if q := p.byOrder[orderId]; q != nil {
+		if q.currency != tr.paidSymbol {
+			return nil
+		}
+		return q
+	}
+	for i, q := range p.pending {
+		if q.side == tr.side && q.target == tr.target && q.base == tr.base && q.price == tr.price &&
+			q.currency == tr.paidSymbol && q.outstanding.Add(tr.paid) <= 0 {
+			p.pending = append(p.pending[:i], p.pending[i+1:]...)
+			p.byOrder[orderId] = q
+			return q
+		}
+	}
+	return nil
+}
+
+// settle leaves the outstanding funds in the placement.
+func (q *placement) settle() {
+	q.t.Amounts = map[s.Symbol]s.Decimal{}
+	if q.outstanding != 0 {
+		q.t.Amounts[q.currency] = q.outstanding
+	}
+}
+
+// parsePlacement parses the description of a placed order, or returns nil.
+func parsePlacement(t *s.Transaction, currency s.Symbol, amount s.Decimal, desc string) *placement {
+	m := placedDesc.FindStringSubmatch(desc)
+	if m == nil {
+		return nil
+	}
+	price, err := s.ParseDecimal(m[4])
+	if err != nil {
+		return nil
+	}
+	q := &placement{
+		t:           t,
+		side:        s.Sell,
+		target:      s.Symbol(strings.ToUpper(m[3])),
+		base:        s.Symbol(strings.ToUpper(m[5])),
+		price:       price,
+		currency:    currency,
+		outstanding: amount,
+	}
+	if strings.EqualFold(m[1], "buy") {
+		q.side = s.Buy
+	}
+	return q
+}
+
+// trade is a trade parsed from its description.
+type trade struct {
+	side         s.TradeType
+	target, base s.Symbol
+	price        s.Decimal
+	// The currency received, and the amount paid in the other currency.
+	received, paidSymbol s.Symbol
+	paid                 s.Decimal
+}
+
+// parseTrade parses the description of a trade, and tells whether it is one.
+func parseTrade(desc string) (tr trade, ok bool) {
+	m, mp := tradeDesc.FindStringSubmatch(desc), priceDesc.FindStringSubmatch(desc)
+	if m == nil || mp == nil {
+		return
+	}
+	quantity, err := s.ParseDecimal(m[2])
+	if err != nil {
+		return
+	}
+	if tr.price, err = s.ParseDecimal(mp[1]); err != nil {
+		return
+	}
+	tr.target, tr.base = s.Symbol(strings.ToUpper(m[3])), s.Symbol(strings.ToUpper(mp[2]))
+	total := quantity.Mul(tr.price)
+	if mt := totalDesc.FindStringSubmatch(desc); mt != nil && strings.EqualFold(mt[2], string(tr.base)) {
+		if total, err = s.ParseDecimal(mt[1]); err != nil {
+			return
+		}
+	}
+	tr.side, tr.received, tr.paidSymbol, tr.paid = s.Sell, tr.base, tr.target, quantity
+	if strings.EqualFold(m[1], "bought") {
+		tr.side, tr.received, tr.paidSymbol, tr.paid = s.Buy, tr.target, tr.base, total
+	}
+	return tr, true
+}
@@ -0,0 +1,140 @@
+package btce
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	s "github.com/thinxer/coincross"
+)
+
+func d(v string) s.Decimal {
+	x, err := s.ParseDecimal(v)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// history is a TransHistory in the format of BTC-E, oldest first.
+var history = []transEntry{
+	{Id: 1, Type: 1, Amount: d("100"), Currency: "USD", Desc: "USD Payment"},
+	// A buy order filled at once.
+	{Id: 2, Type: 5, Amount: d("80"), Currency: "USD", Desc: "Buy 0.1 BTC (BTC/USD) at 800 USD"},
+	{Id: 3, Type: 4, Amount: d("0.0998"), Currency: "BTC", Desc: "Bought 0.1 BTC from your order :order:11: by price 800 USD total 80 USD (-0.2%)"},
+	// A sell order partially filled, and cancelled.
+	{Id: 4, Type: 5, Amount: d("0.05"), Currency: "BTC", Desc: "Sell 0.05 BTC (BTC/USD) at 900 USD"},
+	{Id: 5, Type: 4, Amount: d("17.964"), Currency: "USD", Desc: "Sold 0.02 BTC from your order :order:12: by price 900 USD total 18 USD (-0.2%)"},
+	{Id: 6, Type: 4, Amount: d("0.03"), Currency: "BTC", Desc: "Cancel order :order:12:"},
+	// A buy order still open.
+	{Id: 7, Type: 5, Amount: d("10"), Currency: "USD", Desc: "Buy 0.1 BTC (BTC/USD) at 100 USD"},
+	{Id: 8, Type: 2, Amount: d("0.01"), Currency: "BTC", Desc: "BTC Withdrawal, fee 0.001 BTC"},
+	{Id: 9, Type: 5, Amount: d("0.001"), Currency: "BTC", Desc: "Withdrawal fee"},
+	// A trade which is not understood.
+	{Id: 10, Type: 5, Amount: d("1"), Currency: "USD", Desc: "Buy 0.01 BTC (BTC/USD) at 100 USD"},
+	{Id: 11, Type: 4, Amount: d("0.00998"), Currency: "BTC", Desc: "Bought 0.01 BTC from your order :order:13: at 100 USD"},
+}
+
+func TestParseTransactions(t *testing.T) {
+	entries := append([]transEntry(nil), history...)
+	transactions := parseTransactions(entries)
+	if len(transactions) != len(history) {
+		t.Fatalf("got %d transactions, want %d", len(transactions), len(history))
+	}
+	byId := make(map[int64]s.Transaction)
+	for i, tr := range transactions {
+		if i > 0 && tr.Id > transactions[i-1].Id {
+			t.Fatal("not the latest first")
+		}
+		byId[tr.Id] = tr
+	}
+
+	tests := []struct {
+		id      int64
+		kind    s.TransactionKind
+		amounts map[s.Symbol]string
+	}{
+		{1, s.TransactionDeposit, map[s.Symbol]string{s.USD: "100"}},
+		{2, s.TransactionOrderPlaced, map[s.Symbol]string{}},
+		{3, s.TransactionBuy, map[s.Symbol]string{s.BTC: "0.0998", s.USD: "-80"}},
+		{4, s.TransactionOrderPlaced, map[s.Symbol]string{}},
+		{5, s.TransactionSell, map[s.Symbol]string{s.USD: "17.964", s.BTC: "-0.02"}},
+		{6, s.TransactionOrderCancelled, map[s.Symbol]string{}},
+		{7, s.TransactionOrderPlaced, map[s.Symbol]string{s.USD: "-10"}},
+		{8, s.TransactionWithdrawal, map[s.Symbol]string{s.BTC: "-0.01"}},
+		{9, s.TransactionFee, map[s.Symbol]string{s.BTC: "-0.001"}},
+		{10, s.TransactionOrderPlaced, map[s.Symbol]string{s.USD: "-1"}},
+		{11, s.TransactionUnknown, map[s.Symbol]string{s.BTC: "0.00998"}},
+	}
+	for _, test := range tests {
+		tr := byId[test.id]
+		if tr.Kind != test.kind {
+			t.Errorf("%d: got kind %s, want %s", test.id, tr.Kind, test.kind)
+		}
+		if len(tr.Amounts) != len(test.amounts) {
+			t.Errorf("%d: got amounts %v, want %v", test.id, tr.Amounts, test.amounts)
+			continue
+		}
+		for symbol, amount := range test.amounts {
+			if tr.Amounts[symbol] != d(amount) {
+				t.Errorf("%d: got amounts %v, want %v", test.id, tr.Amounts, test.amounts)
+			}
+		}
+	}
+}
+
+// The amounts must add up as recorded, whatever is understood.
+func TestParseTransactionsSums(t *testing.T) {
+	for n := 1; n <= len(history); n++ {
+		// The latest n entries, as returned with count n.
+		entries := append([]transEntry(nil), history[len(history)-n:]...)
+		want := make(map[s.Symbol]s.Decimal)
+		for _, e := range entries {
+			want[s.Symbol(e.Currency)] += e.signed()
+		}
+		got := make(map[s.Symbol]s.Decimal)
+		for _, tr := range parseTransactions(entries) {
+			for symbol, amount := range tr.Amounts {
+				got[symbol] += amount
+			}
+		}
+		for symbol := range want {
+			if got[symbol] != want[symbol] {
+				t.Errorf("latest %d: got %s %s, want %s", n, got[symbol], symbol, want[symbol])
+			}
+		}
+	}
+}
+
+func TestParseTradeReordered(t *testing.T) {
+	tr, ok := parseTrade("Sold 0.5 LTC total 0.01 BTC from your order :order:7: by price 0.02 BTC")
+	if !ok {
+		t.Fatal("not parsed")
+	}
+	if tr.side != s.Sell || tr.target != s.LTC || tr.base != s.BTC || tr.price != d("0.02") ||
+		tr.received != s.BTC || tr.paidSymbol != s.LTC || tr.paid != d("0.5") {
+		t.Errorf("got %+v", tr)
+	}
+}
+
+func TestTradeFill(t *testing.T) {
+	var reply map[string]tradeEntry
+	err := json.Unmarshal([]byte(`{
+		"1": {"pair": "btc_usd", "type": "buy", "amount": 0.1, "rate": 800, "order_id": 11, "is_your_order": 1, "timestamp": 100},
+		"2": {"pair": "btc_usd", "type": "sell", "amount": 0.2, "rate": 900, "order_id": 12, "is_your_order": 0, "timestamp": 200}
+	}`), &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []s.Fill{
+		{OrderId: 11, TradeId: 1, Side: s.Buy, Pair: s.BTC_USD, Price: d("800"), Amount: d("0.1"), Timestamp: 100, Liquidity: s.Maker},
+		// Taken from the order of someone else.
+		{OrderId: 0, TradeId: 2, Side: s.Sell, Pair: s.BTC_USD, Price: d("900"), Amount: d("0.2"), Timestamp: 200, Liquidity: s.Taker},
+	}
+	for _, want := range tests {
+		id := strconv.FormatInt(want.TradeId, 10)
+		if got := reply[id].fill(id); got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package coincross
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint is the position of a trade stream after a delivered trade.
+type Checkpoint struct {
+	// Since is given to Client.History when resuming.
+	Since int64
+	// The id and timestamp of the last delivered trade.
+	LastId, Timestamp int64
+}
+
+// Checkpointer saves the position of a trade stream, so that it can be
+// resumed after a restart.
+type Checkpointer interface {
+	// LoadCheckpoint returns the saved checkpoint. ok is false if none.
+	LoadCheckpoint() (cp Checkpoint, ok bool, err error)
+	SaveCheckpoint(cp Checkpoint) error
+}
+
+// FileCheckpoint is a Checkpointer saving to a JSON file.
+type FileCheckpoint string
+
+func (f FileCheckpoint) LoadCheckpoint() (cp Checkpoint, ok bool, err error) {
+	content, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return cp, false, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &cp)
+	}
+	return cp, err == nil, err
+}
+
+// SaveCheckpoint replaces the file atomically, so a crash never leaves a
+// partially written checkpoint.
+func (f FileCheckpoint) SaveCheckpoint(cp Checkpoint) error {
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(string(f), content)
+}
+
+// writeFileAtomic replaces the file with content through a temporary file.
+func writeFileAtomic(name string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	return err
+}
@@ -16,6 +16,10 @@ changes.
 */
 package coincross
 
+import (
+	"context"
+)
+
 // Symbol represents a currency, such as USD, CNY, BTC etc.
 type Symbol string
 
@@ -53,7 +57,7 @@ const (
 
 // Ticker represents for the result of Ticker APIs.
 type Ticker struct {
-	Buy, Sell, High, Low, Last, Volume float64
+	Buy, Sell, High, Low, Last, Volume Decimal
 }
 
 // A historical trade instance.
@@ -63,8 +67,8 @@ type Trade struct {
 	Id        int64
 	Timestamp int64
 	Type      TradeType
-	Price     float64
-	Amount    float64
+	Price     Decimal
+	Amount    Decimal
 	Pair      Pair
 }
 
@@ -73,9 +77,35 @@ type Order struct {
 	Id             int64
 	Timestamp      int64
 	Type           TradeType
-	Price          float64
-	Remain, Amount float64
+	Price          Decimal
+	Remain, Amount Decimal
 	Pair           Pair
+	Status         OrderStatus
+	// The id given in OrderRequest, if known. See WithJournal.
+	ClientOrderId string
+}
+
+// OrderStatus is the state of an order.
+type OrderStatus int
+
+const (
+	// The exchange does not tell.
+	StatusUnknown OrderStatus = iota
+	StatusOpen
+	// Open and partially filled.
+	StatusPartial
+	StatusFilled
+	// Cancelled, possibly after partial fills.
+	StatusCancelled
+	StatusRejected
+)
+
+// OrderGetter is implemented by clients that can look up a single order,
+// including the closed ones.
+type OrderGetter interface {
+	// GetOrder returns the order of the id, or an error matching
+	// ErrOrderNotFound.
+	GetOrder(orderId int64) (Order, error)
 }
 
 // A transaction is an operation to your account's balance.
@@ -83,28 +113,43 @@ type Order struct {
 type Transaction struct {
 	Id          int64
 	Timestamp   int64
-	Amounts     map[Symbol]float64
+	Amounts     map[Symbol]Decimal
 	Descritpion string
+	Kind        TransactionKind
 }
 
+// TransactionKind is the kind of a Transaction.
+type TransactionKind int
+
+const (
+	TransactionUnknown TransactionKind = iota
+	TransactionDeposit
+	TransactionWithdrawal
+	// A trade, with the amount received, and the amount paid if known.
+	TransactionBuy
+	TransactionSell
+	TransactionFee
+	// Placing an order moves funds into it, and cancelling it moves the rest
+	// back. Where the trades of an order tell the amounts paid, these are
+	// taken off its placement, and so is its cancellation, so that only the
+	// funds still in the order are left in the placement.
+	TransactionOrderPlaced
+	TransactionOrderCancelled
+)
+
 // Well, the order book, or the market depth.
 type Orderbook struct {
 	Asks, Bids []struct {
-		Price, Amount float64
+		Price, Amount Decimal
 	}
 }
 
-type Streamer struct {
-	C       <-chan Trade
-	Closing chan<- bool
-}
-
 // This is the interface that every API implementation should use.
 type Client interface {
 	// Should return the balance of current account.
-	Balance() (map[Symbol]float64, error)
+	Balance() (map[Symbol]Decimal, error)
 	// Use with caution: this method is for real trading.
-	Trade(tradeType TradeType, pair Pair, price, amount float64) (int64, error)
+	Trade(tradeType TradeType, pair Pair, price, amount Decimal) (int64, error)
 	// Cancel an active order.
 	Cancel(orderId int64) (bool, error)
 	// Returns active orders.
@@ -125,3 +170,18 @@ type Client interface {
 	// History streaming.
 	Stream(pair Pair, since int64) *Streamer
 }
+
+// ContextClient is the context-aware variant of Client.
+// The context can be used to cancel a call or to set a per-call deadline.
+// Implementations should return ctx.Err() when the context is done.
+type ContextClient interface {
+	BalanceContext(ctx context.Context) (map[Symbol]Decimal, error)
+	TradeContext(ctx context.Context, tradeType TradeType, pair Pair, price, amount Decimal) (int64, error)
+	CancelContext(ctx context.Context, orderId int64) (bool, error)
+	OrdersContext(ctx context.Context) ([]Order, error)
+	TransactionsContext(ctx context.Context, limit int) ([]Transaction, error)
+
+	OrderbookContext(ctx context.Context, pair Pair, limit int) (*Orderbook, error)
+	HistoryContext(ctx context.Context, pair Pair, since int64) ([]Trade, int64, error)
+	TickerContext(ctx context.Context, pair Pair) (*Ticker, error)
+}
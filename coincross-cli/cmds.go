@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
+	"time"
 	"code.google.com/p/go-commander"
 
 	s "github.com/thinxer/coincross"
@@ -22,25 +28,75 @@ func init() {
 	}
 }
 
-func trade(c s.Client, tradeType s.TradeType, args []string) {
-	price := must(strconv.ParseFloat(args[0], 64)).(float64)
-	amount := must(strconv.ParseFloat(args[1], 64)).(float64)
-	id, err := client.Trade(tradeType, flagPair, price, amount)
+// tradeFlags are the flags of buy and sell.
+type tradeFlags struct {
+	round   *bool
+	tif     s.TimeInForce
+	id      *string
+	journal *string
+}
+
+func newTradeFlags(cmd *commander.Command) *tradeFlags {
+	f := new(tradeFlags)
+	f.round = (&cmd.Flag).Bool("round", false, "round to the market precision")
+	cmd.Flag.Var(&f.tif, "tif", "time in force: GTC, IOC, FOK or post-only")
+	f.id = (&cmd.Flag).String("id", "", "client order id, which makes placing the order again safe")
+	f.journal = (&cmd.Flag).String("journal", "coincross-orders.json", "journal of the client order ids")
+	return f
+}
+
+func trade(c s.Client, tradeType s.TradeType, flags *tradeFlags, args []string) {
+	round, tif := *flags.round, flags.tif
+	req := s.OrderRequest{Side: tradeType, Pair: flagPair, TimeInForce: tif, ClientOrderId: *flags.id}
+	if args[0] == "market" {
+		req.Type = s.MarketOrder
+	} else {
+		req.Price = must(s.ParseDecimal(args[0])).(s.Decimal)
+	}
+	req.Amount = must(s.ParseDecimal(args[1])).(s.Decimal)
+	if req.Type == s.LimitOrder {
+		if round {
+			order, err := s.NormalizeTrade(c, tradeType, flagPair, req.Price, req.Amount)
+			check(err)
+			req.Price, req.Amount = order.Price, order.Amount
+		} else if err := s.ValidateTrade(c, tradeType, flagPair, req.Price, req.Amount); err != nil {
+			// Without the market rules, the exchange validates the order.
+			var invalid *s.ValidationError
+			if errors.As(err, &invalid) {
+				check(err)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: order not validated: %v\n", err)
+		}
+	}
+	if req.ClientOrderId != "" {
+		journal, err := s.NewFileJournal(*flags.journal)
+		check(err)
+		defer journal.Close()
+		c = s.WithJournal(c, journal)
+	} else if req.Type == s.LimitOrder && tif == s.GTC {
+		id, err := c.Trade(tradeType, flagPair, req.Price, req.Amount)
+		check(err)
+		fmt.Println(id)
+		return
+	}
+	order, err := s.PlaceOrder(c, req)
 	check(err)
-	fmt.Println(id)
+	fmt.Println(order)
 }
 
 func init() {
-	cmd := newCmd("buy", "price amount")
+	cmd := newCmd("buy", "[-round] [-tif GTC] [-id id] price|market amount")
+	flags := newTradeFlags(cmd)
 	cmd.Run = func(cmd *commander.Command, args []string) {
-		trade(client, s.Buy, args)
+		trade(client, s.Buy, flags, args)
 	}
 }
 
 func init() {
-	cmd := newCmd("sell", "price amount")
+	cmd := newCmd("sell", "[-round] [-tif GTC] [-id id] price|market amount")
+	flags := newTradeFlags(cmd)
 	cmd.Run = func(cmd *commander.Command, args []string) {
-		trade(client, s.Sell, args)
+		trade(client, s.Sell, flags, args)
 	}
 }
 
@@ -56,8 +112,36 @@ func init() {
 }
 
 func init() {
-	cmd := newCmd("cancel", "orderid")
+	cmd := newCmd("order", "orderid")
 	cmd.Run = func(cmd *commander.Command, args []string) {
+		getter, ok := client.(s.OrderGetter)
+		if !ok {
+			check(fmt.Errorf("order lookup not supported"))
+		}
+		orderId := must(strconv.ParseInt(args[0], 10, 64)).(int64)
+		order, err := getter.GetOrder(orderId)
+		check(err)
+		fmt.Println(order)
+	}
+}
+
+func init() {
+	cmd := newCmd("cancel", "[-all] [-concurrency 4] orderid")
+	all := (&cmd.Flag).Bool("all", false, "cancel all the orders of the pair")
+	concurrency := (&cmd.Flag).Int("concurrency", 4, "")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		if *all {
+			results, err := s.CancelAll(client, flagPair, s.BatchOptions{Concurrency: *concurrency})
+			for _, r := range results {
+				if r.Err != nil {
+					fmt.Printf("%s\tError: %v\n", r.Order, r.Err)
+				} else {
+					fmt.Printf("%s\tcancelled\n", r.Order)
+				}
+			}
+			check(err)
+			return
+		}
 		orderId := must(strconv.ParseInt(args[0], 10, 64)).(int64)
 		ok, err := client.Cancel(orderId)
 		check(err)
@@ -65,6 +149,82 @@ func init() {
 	}
 }
 
+// parseOrders reads the orders of batch, one per line:
+//
+//	buy|sell price|market amount [time-in-force [client-order-id]]
+//
+// Empty lines and lines starting with # are skipped.
+func parseOrders(r io.Reader) (reqs []s.OrderRequest, err error) {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 3 || len(fields) > 5 {
+			return nil, fmt.Errorf("line %d: expecting side, price, amount, [time in force] and [id]", line)
+		}
+		req := s.OrderRequest{Pair: flagPair}
+		if err = req.Side.Set(fields[0]); err == nil {
+			if fields[1] == "market" {
+				req.Type = s.MarketOrder
+			} else {
+				req.Price, err = s.ParseDecimal(fields[1])
+			}
+		}
+		if err == nil {
+			req.Amount, err = s.ParseDecimal(fields[2])
+		}
+		if err == nil && len(fields) > 3 {
+			err = req.TimeInForce.Set(fields[3])
+		}
+		if len(fields) > 4 {
+			req.ClientOrderId = fields[4]
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, scanner.Err()
+}
+
+func init() {
+	cmd := newCmd("batch", "[-concurrency 4] [-journal file] [file]")
+	concurrency := (&cmd.Flag).Int("concurrency", 4, "")
+	journal := (&cmd.Flag).String("journal", "coincross-orders.json", "journal of the client order ids")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		in := os.Stdin
+		if len(args) > 0 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			check(err)
+			defer f.Close()
+			in = f
+		}
+		reqs, err := parseOrders(in)
+		check(err)
+		c := client
+		for _, req := range reqs {
+			if req.ClientOrderId != "" {
+				j, err := s.NewFileJournal(*journal)
+				check(err)
+				defer j.Close()
+				c = s.WithJournal(client, j)
+				break
+			}
+		}
+		results, err := s.PlaceOrders(c, reqs, s.BatchOptions{Concurrency: *concurrency})
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Printf("%s %s %s\tError: %v\n", r.Request.Side, r.Request.Price, r.Request.Amount, r.Err)
+			} else {
+				fmt.Println(r.Order)
+			}
+		}
+		check(err)
+	}
+}
+
 func init() {
 	cmd := newCmd("transactions", "[-limit 50]")
 	limit := (&cmd.Flag).Int("limit", 50, "")
@@ -96,14 +256,44 @@ func init() {
 	cmd.Run = func(cmd *commander.Command, args []string) {
 		orders, err := client.Orderbook(flagPair, *limit)
 		check(err)
-		fmt.Println("Amount\t\tAsks\t\tBids\t\tAmount")
-		min := len(orders.Asks)
-		if len(orders.Bids) < min {
-			min = len(orders.Bids)
-		}
-		for i := 0; i < min; i++ {
-			fmt.Printf("%-16.12g%-16.12g%-16.12g%-16.12g\n", orders.Asks[i].Amount, orders.Asks[i].Price, orders.Bids[i].Price, orders.Bids[i].Amount)
+		printOrderbook(orders)
+	}
+}
+
+func printOrderbook(orders *s.Orderbook) {
+	fmt.Println("Amount\t\tAsks\t\tBids\t\tAmount")
+	min := len(orders.Asks)
+	if len(orders.Bids) < min {
+		min = len(orders.Bids)
+	}
+	for i := 0; i < min; i++ {
+		fmt.Printf("%-16s%-16s%-16s%-16s\n", orders.Asks[i].Amount, orders.Asks[i].Price, orders.Bids[i].Price, orders.Bids[i].Amount)
+	}
+}
+
+// redraw clears the terminal.
+func redraw() {
+	fmt.Print("\033[H\033[2J")
+}
+
+func printErrors(errors <-chan error) {
+	for err := range errors {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+func init() {
+	cmd := newCmd("watch-orderbook", "[-limit 20] [-interval 2s]")
+	limit := (&cmd.Flag).Int("limit", 20, "")
+	interval := (&cmd.Flag).Duration("interval", 2*time.Second, "")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		stream := s.PollOrderbook(context.Background(), client, flagPair, *limit, s.PollOptions{Interval: *interval})
+		go printErrors(stream.Errors())
+		for orders := range stream.C {
+			redraw()
+			printOrderbook(orders)
 		}
+		check(stream.Err())
 	}
 }
 
@@ -111,9 +301,49 @@ func init() {
 	cmd := newCmd("watch", "")
 	cmd.Run = func(cmd *commander.Command, args []string) {
 		streamer := client.Stream(flagPair, -1)
+		go func() {
+			for err := range streamer.Errors() {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}()
+		go func() {
+			for gap := range streamer.Gaps() {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", gap)
+			}
+		}()
 		for t := range streamer.C {
 			fmt.Println(t)
 		}
+		check(streamer.Err())
+	}
+}
+
+func init() {
+	cmd := newCmd("merge", "[-lateness=5s] exchange:BTC/USD...")
+	lateness := (&cmd.Flag).Duration("lateness", 5*time.Second, "")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		var sources []s.StreamSource
+		for _, arg := range args {
+			parts := strings.SplitN(arg, ":", 2)
+			if len(parts) != 2 {
+				check(fmt.Errorf("invalid source: %q", arg))
+			}
+			var pair s.Pair
+			check(pair.Set(parts[1]))
+			c, err := s.New(parts[0], s.WithTransport(s.TimeoutTransport(flagTimeout, flagTimeout)))
+			check(err)
+			sources = append(sources, s.StreamSource{Exchange: parts[0], Client: c, Pair: pair, Since: -1})
+		}
+		merged, err := s.MergeStreams(s.MergeOptions{Lateness: *lateness}, sources...)
+		check(err)
+		go func() {
+			for err := range merged.Errors() {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}()
+		for t := range merged.C {
+			fmt.Println(t)
+		}
 	}
 }
 
@@ -126,6 +356,65 @@ func init() {
 	}
 }
 
+func init() {
+	cmd := newCmd("watch-ticker", "[-interval 2s]")
+	interval := (&cmd.Flag).Duration("interval", 2*time.Second, "")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		stream := s.PollTicker(context.Background(), client, flagPair, s.PollOptions{Interval: *interval})
+		go printErrors(stream.Errors())
+		for ticker := range stream.C {
+			redraw()
+			fmt.Printf("%s\t%s\n", flagPair, time.Now().Format("15:04:05"))
+			fmt.Printf("Last\t%s\nBuy\t%s\nSell\t%s\nHigh\t%s\nLow\t%s\nVolume\t%s\n", ticker.Last, ticker.Buy, ticker.Sell, ticker.High, ticker.Low, ticker.Volume)
+		}
+		check(stream.Err())
+	}
+}
+
+func init() {
+	cmd := newCmd("watch-orders", "[-interval 5s]")
+	interval := (&cmd.Flag).Duration("interval", 5*time.Second, "")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		watcher := s.WatchOrders(context.Background(), client, s.PollOptions{Interval: *interval})
+		go printErrors(watcher.Errors())
+		for e := range watcher.C {
+			fmt.Println(e)
+		}
+		check(watcher.Err())
+	}
+}
+
+func init() {
+	cmd := newCmd("fills", "[-since 24h]")
+	since := (&cmd.Flag).Duration("since", 24*time.Hour, "")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		lister, ok := client.(s.FillLister)
+		if !ok {
+			check(fmt.Errorf("fills not supported"))
+		}
+		fills, err := lister.Fills(flagPair, time.Now().Add(-*since).Unix())
+		check(err)
+		for _, f := range fills {
+			fmt.Println(f)
+		}
+	}
+}
+
+func init() {
+	cmd := newCmd("markets", "")
+	cmd.Run = func(cmd *commander.Command, args []string) {
+		lister, ok := client.(s.MarketLister)
+		if !ok {
+			check(fmt.Errorf("markets not supported"))
+		}
+		markets, err := lister.Markets()
+		check(err)
+		for _, m := range markets {
+			fmt.Println(m)
+		}
+	}
+}
+
 func check(err error) {
 	if err != nil {
 		_, file, line, _ := runtime.Caller(1)
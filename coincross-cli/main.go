@@ -47,9 +47,12 @@ func main() {
 	exchange := os.Getenv("EXCHANGE")
 	apikey := os.Getenv("APIKEY")
 	secret := os.Getenv("SECRET")
-	client = s.New(exchange, apikey, secret, s.TimeoutTransport(flagTimeout, flagTimeout))
-	if client == nil {
-		panic("create client failed")
+	var err error
+	client, err = s.New(exchange,
+		s.WithCredentials(apikey, secret),
+		s.WithTransport(s.TimeoutTransport(flagTimeout, flagTimeout)))
+	if err != nil {
+		panic(err)
 	}
 
 	// Actually run the commands
@@ -0,0 +1,105 @@
+package coincross
+
+import (
+	"context"
+)
+
+// AsContextClient returns a ContextClient for c.
+//
+// If c already implements ContextClient, it is returned as is. Otherwise every
+// call is made in its own goroutine, and returns with ctx.Err() as soon as ctx
+// is done. Note that in this case the underlying request is not aborted, its
+// result is simply discarded.
+func AsContextClient(c Client) ContextClient {
+	if cc, ok := c.(ContextClient); ok {
+		return cc
+	}
+	return contextAdapter{c}
+}
+
+type contextAdapter struct {
+	c Client
+}
+
+// await runs f in a new goroutine and waits for it or for ctx to be done.
+func await(ctx context.Context, f func() (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type result struct {
+		v   interface{}
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := f()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (a contextAdapter) BalanceContext(ctx context.Context) (map[Symbol]Decimal, error) {
+	v, err := await(ctx, func() (interface{}, error) { return a.c.Balance() })
+	balance, _ := v.(map[Symbol]Decimal)
+	return balance, err
+}
+
+func (a contextAdapter) TradeContext(ctx context.Context, tradeType TradeType, pair Pair, price, amount Decimal) (int64, error) {
+	v, err := await(ctx, func() (interface{}, error) { return a.c.Trade(tradeType, pair, price, amount) })
+	orderId, ok := v.(int64)
+	if !ok {
+		orderId = -1
+	}
+	return orderId, err
+}
+
+func (a contextAdapter) CancelContext(ctx context.Context, orderId int64) (bool, error) {
+	v, err := await(ctx, func() (interface{}, error) { return a.c.Cancel(orderId) })
+	success, _ := v.(bool)
+	return success, err
+}
+
+func (a contextAdapter) OrdersContext(ctx context.Context) ([]Order, error) {
+	v, err := await(ctx, func() (interface{}, error) { return a.c.Orders() })
+	orders, _ := v.([]Order)
+	return orders, err
+}
+
+func (a contextAdapter) TransactionsContext(ctx context.Context, limit int) ([]Transaction, error) {
+	v, err := await(ctx, func() (interface{}, error) { return a.c.Transactions(limit) })
+	transactions, _ := v.([]Transaction)
+	return transactions, err
+}
+
+func (a contextAdapter) OrderbookContext(ctx context.Context, pair Pair, limit int) (*Orderbook, error) {
+	v, err := await(ctx, func() (interface{}, error) { return a.c.Orderbook(pair, limit) })
+	orderbook, _ := v.(*Orderbook)
+	return orderbook, err
+}
+
+func (a contextAdapter) HistoryContext(ctx context.Context, pair Pair, since int64) ([]Trade, int64, error) {
+	type history struct {
+		trades []Trade
+		next   int64
+	}
+	v, err := await(ctx, func() (interface{}, error) {
+		trades, next, err := a.c.History(pair, since)
+		return history{trades, next}, err
+	})
+	h, ok := v.(history)
+	if !ok {
+		h.next = since
+	}
+	return h.trades, h.next, err
+}
+
+func (a contextAdapter) TickerContext(ctx context.Context, pair Pair) (*Ticker, error) {
+	v, err := await(ctx, func() (interface{}, error) { return a.c.Ticker(pair) })
+	ticker, _ := v.(*Ticker)
+	return ticker, err
+}
@@ -0,0 +1,193 @@
+package coincross
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+)
+
+// Decimal is a fixed-point decimal number with DecimalPlaces decimal places.
+// It is used for prices and amounts, where float64 may lose precision.
+// The zero value is 0, and Decimals can be compared with == and <.
+type Decimal int64
+
+// DecimalPlaces is the number of decimal places a Decimal holds.
+// 8 is enough for a satoshi.
+const DecimalPlaces = 8
+
+const decimalScale = 100000000
+
+var bigScale = big.NewInt(decimalScale)
+
+// NewDecimal returns the Decimal nearest to f.
+// It panics if f is NaN or out of the range of Decimal.
+func NewDecimal(f float64) Decimal {
+	v := math.Round(f * decimalScale)
+	// -2^63 is the least int64, and 2^63 is just above the greatest.
+	if math.IsNaN(v) || v < -(1<<63) || v >= 1<<63 {
+		panic(fmt.Sprintf("coincross: NewDecimal(%g) out of range", f))
+	}
+	return Decimal(v)
+}
+
+// The decimal strings accepted by ParseDecimal.
+var decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]{1,3})?$`)
+
+// ParseDecimal parses a decimal string such as "-12.345" or "1e-5".
+// Digits beyond DecimalPlaces are rounded half away from zero.
+func ParseDecimal(s string) (Decimal, error) {
+	if !decimalPattern.MatchString(s) {
+		return 0, fmt.Errorf("invalid decimal: %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid decimal: %q", s)
+	}
+	d, ok := ratDecimal(r)
+	if !ok {
+		return 0, fmt.Errorf("decimal out of range: %q", s)
+	}
+	return d, nil
+}
+
+// ratDecimal rounds r to the nearest Decimal.
+func ratDecimal(r *big.Rat) (Decimal, bool) {
+	r = new(big.Rat).Mul(r, new(big.Rat).SetInt(bigScale))
+	n := roundQuo(r.Num(), r.Denom())
+	if !n.IsInt64() {
+		return 0, false
+	}
+	return Decimal(n.Int64()), true
+}
+
+// bigDecimal converts n, panicking if it is out of the range of Decimal.
+func bigDecimal(n *big.Int, op string) Decimal {
+	if !n.IsInt64() {
+		panic("coincross: Decimal overflow in " + op)
+	}
+	return Decimal(n.Int64())
+}
+
+// roundQuo returns x/y rounded half away from zero. y must be positive.
+func roundQuo(x, y *big.Int) *big.Int {
+	q, m := new(big.Int).QuoRem(x, y, new(big.Int))
+	if m.Abs(m).Lsh(m, 1).Cmp(y) >= 0 {
+		if x.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// Float64 returns the nearest float64 value of d.
+func (d Decimal) Float64() float64 {
+	return float64(d) / decimalScale
+}
+
+func (d Decimal) Add(e Decimal) Decimal {
+	return d + e
+}
+
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d - e
+}
+
+func (d Decimal) Neg() Decimal {
+	return -d
+}
+
+func (d Decimal) Abs() Decimal {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Sign returns -1, 0 or 1.
+func (d Decimal) Sign() int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+func (d Decimal) IsZero() bool {
+	return d == 0
+}
+
+// Cmp returns -1 if d < e, 0 if d == e, and 1 if d > e.
+func (d Decimal) Cmp(e Decimal) int {
+	return d.Sub(e).Sign()
+}
+
+// Mul returns d*e, rounded half away from zero.
+// It panics if the result is out of the range of Decimal.
+func (d Decimal) Mul(e Decimal) Decimal {
+	x := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(e)))
+	return bigDecimal(roundQuo(x, bigScale), "Mul")
+}
+
+// Div returns d/e, rounded half away from zero. It panics if e is zero, or
+// if the result is out of the range of Decimal.
+func (d Decimal) Div(e Decimal) Decimal {
+	x := new(big.Int).Mul(big.NewInt(int64(d)), bigScale)
+	y := big.NewInt(int64(e))
+	if y.Sign() < 0 {
+		x.Neg(x)
+		y.Neg(y)
+	}
+	return bigDecimal(roundQuo(x, y), "Div")
+}
+
+// Round rounds d half away from zero to the given decimal places.
+func (d Decimal) Round(places int) Decimal {
+	if places >= DecimalPlaces {
+		return d
+	}
+	unit := DecimalUnit(places)
+	return Decimal(roundQuo(big.NewInt(int64(d)), big.NewInt(int64(unit))).Int64()) * unit
+}
+
+// Truncate rounds d toward zero to the given decimal places.
+func (d Decimal) Truncate(places int) Decimal {
+	if places >= DecimalPlaces {
+		return d
+	}
+	unit := DecimalUnit(places)
+	return d / unit * unit
+}
+
+// Floor returns the greatest multiple of step that is not greater than d.
+// d is returned as is if step is not positive.
+func (d Decimal) Floor(step Decimal) Decimal {
+	if step <= 0 {
+		return d
+	}
+	q := d / step * step
+	if q > d {
+		q -= step
+	}
+	return q
+}
+
+// Ceil returns the least multiple of step that is not less than d.
+// d is returned as is if step is not positive.
+func (d Decimal) Ceil(step Decimal) Decimal {
+	return -(-d).Floor(step)
+}
+
+// DecimalUnit returns the smallest step with the given decimal places,
+// such as 0.01 for 2.
+func DecimalUnit(places int) Decimal {
+	unit := Decimal(1)
+	for i := places; i < DecimalPlaces; i++ {
+		unit *= 10
+	}
+	return unit
+}
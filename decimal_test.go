@@ -0,0 +1,133 @@
+package coincross
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Decimal
+	}{
+		{"0", 0},
+		{"1", 100000000},
+		{"-12.345", -1234500000},
+		{"+0.5", 50000000},
+		{".5", 50000000},
+		{"5.", 500000000},
+		{"1e-5", 1000},
+		{"1.5E2", 15000000000},
+		{"0.00000001", 1},
+		// Rounded half away from zero.
+		{"0.000000005", 1},
+		{"-0.000000005", -1},
+		{"0.0000000049", 0},
+		{"92233720368.54775807", math.MaxInt64},
+	}
+	for _, test := range tests {
+		got, err := ParseDecimal(test.in)
+		if err != nil || got != test.want {
+			t.Errorf("ParseDecimal(%q) = %d, %v, want %d", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestParseDecimalInvalid(t *testing.T) {
+	for _, in := range []string{
+		"", " 1", "1 ", "abc", "0x10", "0b1", "0o7", "1_000", "1/2", "--1", "1e", "e5", ".", "1e1000",
+		"92233720368.54775808", "Inf", "NaN",
+	} {
+		if d, err := ParseDecimal(in); err == nil {
+			t.Errorf("ParseDecimal(%q) = %s, want an error", in, d)
+		}
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	tests := []struct {
+		in   Decimal
+		want string
+	}{
+		{0, "0"},
+		{1, "0.00000001"},
+		{-150000000, "-1.5"},
+		{1000000000, "10"},
+		{math.MinInt64, "-92233720368.54775808"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("%d.String() = %q, want %q", int64(test.in), got, test.want)
+		}
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct{ A, B, C Decimal }
+	if err := json.Unmarshal([]byte(`{"A": 1.25, "B": "0.5", "C": ""}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 125000000 || v.B != 50000000 || v.C != 0 {
+		t.Errorf("got %+v", v)
+	}
+	if err := json.Unmarshal([]byte(`{"A": "0x10"}`), &v); err == nil {
+		t.Error("hexadecimal accepted")
+	}
+}
+
+func TestDecimalMulDiv(t *testing.T) {
+	d := func(s string) Decimal {
+		x, err := ParseDecimal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return x
+	}
+	tests := []struct {
+		got, want Decimal
+	}{
+		{d("1.5").Mul(d("2")), d("3")},
+		{d("0.00000001").Mul(d("0.5")), d("0.00000001")},
+		{d("-0.00000001").Mul(d("0.5")), d("-0.00000001")},
+		{d("0.00000001").Mul(d("0.4")), 0},
+		{d("1").Div(d("3")), d("0.33333333")},
+		{d("2").Div(d("3")), d("0.66666667")},
+		{d("1").Div(d("-8")), d("-0.125")},
+		{d("1.23456789").Round(2), d("1.23")},
+		{d("1.235").Round(2), d("1.24")},
+		{d("-1.235").Round(2), d("-1.24")},
+		{d("1.239").Truncate(2), d("1.23")},
+		{d("-1.239").Truncate(2), d("-1.23")},
+		{d("1.26").Floor(d("0.05")), d("1.25")},
+		{d("1.26").Ceil(d("0.05")), d("1.3")},
+		{d("-1.26").Floor(d("0.05")), d("-1.3")},
+	}
+	for i, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%d: got %s, want %s", i, test.got, test.want)
+		}
+	}
+}
+
+func expectPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", name)
+		}
+	}()
+	f()
+}
+
+func TestDecimalOverflow(t *testing.T) {
+	price, amount := NewDecimal(1000000), NewDecimal(1000000)
+	expectPanic(t, "Mul", func() { price.Mul(amount) })
+	expectPanic(t, "Div", func() { price.Div(1) })
+	expectPanic(t, "NewDecimal", func() { NewDecimal(1e11) })
+	expectPanic(t, "NewDecimal(NaN)", func() { NewDecimal(math.NaN()) })
+	expectPanic(t, "NewDecimal(Inf)", func() { NewDecimal(math.Inf(-1)) })
+	if got := NewDecimal(0.1); got != 10000000 {
+		t.Errorf("NewDecimal(0.1) = %d", got)
+	}
+}
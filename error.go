@@ -2,8 +2,13 @@ package coincross
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
 )
 
+// TradeError is kept for compatibility. Exchanges return ExchangeError,
+// which can be matched against the errors below with errors.Is.
 type TradeError error
 
 func NewTradeError(text string) TradeError {
@@ -14,4 +19,127 @@ var (
 	ErrInvalidCredential      = NewTradeError("Invalid Credential")
 	ErrInsufficientPermission = NewTradeError("Insufficient Permissions")
 	ErrInsufficientBalance    = NewTradeError("Insufficient Balance")
+	ErrRateLimited            = NewTradeError("Rate Limited")
+	ErrInvalidNonce           = NewTradeError("Invalid Nonce")
+	ErrInvalidOrder           = NewTradeError("Invalid Order")
+	ErrOrderNotFound          = NewTradeError("Order Not Found")
+	ErrTemporary              = NewTradeError("Temporary Failure")
+	// The order has been placed, but its id could not be determined.
+	ErrAmbiguousOrderId = NewTradeError("Ambiguous Order Id")
+	// The exchange cannot carry out the order request.
+	ErrUnsupportedOrder = NewTradeError("Unsupported Order")
 )
+
+// ErrorCategory classifies the errors reported by exchanges.
+type ErrorCategory int
+
+const (
+	CategoryUnknown ErrorCategory = iota
+	CategoryAuth
+	CategoryPermission
+	CategoryInsufficientFunds
+	CategoryRateLimited
+	CategoryNonce
+	CategoryInvalidOrder
+	CategoryOrderNotFound
+	CategoryTemporary
+	CategoryUnsupported
+)
+
+var categoryErrors = map[ErrorCategory]error{
+	CategoryAuth:              ErrInvalidCredential,
+	CategoryPermission:        ErrInsufficientPermission,
+	CategoryInsufficientFunds: ErrInsufficientBalance,
+	CategoryRateLimited:       ErrRateLimited,
+	CategoryNonce:             ErrInvalidNonce,
+	CategoryInvalidOrder:      ErrInvalidOrder,
+	CategoryOrderNotFound:     ErrOrderNotFound,
+	CategoryTemporary:         ErrTemporary,
+	CategoryUnsupported:       ErrUnsupportedOrder,
+}
+
+// Err returns the error matching the category, or nil for CategoryUnknown.
+func (c ErrorCategory) Err() error {
+	return categoryErrors[c]
+}
+
+// ExchangeError is an error reported by an exchange.
+// It matches the error of its category with errors.Is, for example:
+//
+//	if errors.Is(err, coincross.ErrInsufficientBalance) { ... }
+type ExchangeError struct {
+	// The registered name of the exchange, such as "btce", or empty if not
+	// known.
+	Exchange string
+	// The API method that failed.
+	Method string
+	// The raw error code, or the HTTP status code. 0 if not available.
+	Code     int
+	Message  string
+	Category ErrorCategory
+}
+
+func (e *ExchangeError) Error() string {
+	message := fmt.Sprintf("%s: %s", e.Method, e.Message)
+	if e.Exchange != "" {
+		message = e.Exchange + ": " + message
+	}
+	if e.Code != 0 {
+		message = fmt.Sprintf("%s (%d)", message, e.Code)
+	}
+	return message
+}
+
+func (e *ExchangeError) Is(target error) bool {
+	return target != nil && target == e.Category.Err()
+}
+
+// StatusError returns an ExchangeError for a non-2xx HTTP status, or nil.
+func StatusError(exchange, method string, status int) error {
+	if status >= 200 && status < 300 {
+		return nil
+	}
+	category := CategoryUnknown
+	switch {
+	case status == http.StatusUnauthorized:
+		category = CategoryAuth
+	case status == http.StatusForbidden:
+		category = CategoryPermission
+	case status == http.StatusTooManyRequests:
+		category = CategoryRateLimited
+	case status >= 500:
+		category = CategoryTemporary
+	}
+	return &ExchangeError{exchange, method, status, http.StatusText(status), category}
+}
+
+// CategoryOf classifies err. Network timeouts are considered temporary.
+func CategoryOf(err error) ErrorCategory {
+	if err == nil {
+		return CategoryUnknown
+	}
+	var e *ExchangeError
+	if errors.As(err, &e) {
+		return e.Category
+	}
+	for category, target := range categoryErrors {
+		if errors.Is(err, target) {
+			return category
+		}
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return CategoryTemporary
+	}
+	return CategoryUnknown
+}
+
+// IsTemporary tells whether err is likely to go away on retry.
+// Nonce errors are included, as the next request uses a new nonce.
+func IsTemporary(err error) bool {
+	switch CategoryOf(err) {
+	case CategoryTemporary, CategoryRateLimited, CategoryNonce:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package coincross
+
+import (
+	"context"
+)
+
+// Liquidity tells whether a fill made or took liquidity.
+type Liquidity int
+
+const (
+	// The exchange does not tell.
+	LiquidityUnknown Liquidity = iota
+	// The order was on the book, and another order was matched against it.
+	Maker
+	// The order was matched against an order on the book.
+	Taker
+)
+
+// Fill is an execution of an own order.
+type Fill struct {
+	// 0 if unknown.
+	OrderId int64
+	TradeId int64
+	Side    TradeType
+	Pair    Pair
+	Price   Decimal
+	Amount  Decimal
+	// The fee charged in FeeSymbol, 0 if unknown.
+	Fee       Decimal
+	FeeSymbol Symbol
+	Timestamp int64
+	Liquidity Liquidity
+}
+
+// FillLister is implemented by clients that can list the executions of
+// their own orders.
+type FillLister interface {
+	// Fills returns the fills of the pair, or of all pairs if pair is ALL,
+	// since the given time in unix seconds, in the order of execution.
+	// Exchanges may limit the number of fills returned at once.
+	Fills(pair Pair, since int64) ([]Fill, error)
+}
+
+// ContextFillLister is the context-aware variant of FillLister.
+type ContextFillLister interface {
+	FillsContext(ctx context.Context, pair Pair, since int64) ([]Fill, error)
+}
+
+// FillsContext calls lister.FillsContext if lister implements
+// ContextFillLister. Otherwise lister.Fills is called as by AsContextClient.
+func FillsContext(ctx context.Context, lister FillLister, pair Pair, since int64) ([]Fill, error) {
+	if cl, ok := lister.(ContextFillLister); ok {
+		return cl.FillsContext(ctx, pair, since)
+	}
+	v, err := await(ctx, func() (interface{}, error) { return lister.Fills(pair, since) })
+	fills, _ := v.([]Fill)
+	return fills, err
+}
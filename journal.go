@@ -0,0 +1,299 @@
+package coincross
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JournalEntry records an order submitted with a client order id.
+type JournalEntry struct {
+	ClientOrderId string
+	Request       OrderRequest
+	// When the order was first submitted, in unix seconds.
+	Submitted int64
+	// Placed tells that the order is known to be placed, as OrderId, which
+	// is 0 if the order was filled at once on BTC-E.
+	Placed  bool
+	OrderId int64
+	// The status of the order when it was recorded. StatusRejected tells
+	// that the last submission was turned down, so it may be submitted
+	// again.
+	Status OrderStatus
+}
+
+// OrderJournal maps client order ids to the orders on the exchange.
+type OrderJournal interface {
+	// Load returns the entry of the client order id. ok is false if none.
+	Load(clientOrderId string) (entry JournalEntry, ok bool, err error)
+	// Save adds or replaces the entry of its client order id.
+	Save(entry JournalEntry) error
+	Entries() ([]JournalEntry, error)
+}
+
+// MemoryJournal is an OrderJournal kept in memory.
+type MemoryJournal struct {
+	mu      sync.Mutex
+	entries map[string]JournalEntry
+}
+
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{entries: make(map[string]JournalEntry)}
+}
+
+func (j *MemoryJournal) Load(clientOrderId string) (entry JournalEntry, ok bool, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok = j.entries[clientOrderId]
+	return
+}
+
+func (j *MemoryJournal) Save(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries[entry.ClientOrderId] = entry
+	return nil
+}
+
+// Entries returns the entries sorted by client order id.
+func (j *MemoryJournal) Entries() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.sorted(), nil
+}
+
+func (j *MemoryJournal) sorted() []JournalEntry {
+	entries := make([]JournalEntry, 0, len(j.entries))
+	for _, e := range j.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].ClientOrderId < entries[b].ClientOrderId })
+	return entries
+}
+
+// FileJournal is an OrderJournal saved to a JSON file, which is replaced
+// atomically on every Save. The processes sharing the file take turns: it is
+// locked from NewFileJournal until Close, through a ".lock" file next to it,
+// where the platform supports file locking. A file must be opened only once
+// in a process.
+type FileJournal struct {
+	MemoryJournal
+	name string
+	lock *os.File
+}
+
+// NewFileJournal opens the journal in the file, which is created on the
+// first Save if it does not exist. It waits while another process has the
+// journal open.
+func NewFileJournal(name string) (*FileJournal, error) {
+	lock, err := os.OpenFile(name+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	j := &FileJournal{MemoryJournal{entries: make(map[string]JournalEntry)}, name, lock}
+	if err = j.load(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *FileJournal) load() error {
+	content, err := ioutil.ReadFile(j.name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []JournalEntry
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return fmt.Errorf("%s: %v", j.name, err)
+	}
+	for _, e := range entries {
+		j.entries[e.ClientOrderId] = e
+	}
+	return nil
+}
+
+// Close releases the file to the other processes.
+func (j *FileJournal) Close() error {
+	unlockFile(j.lock)
+	return j.lock.Close()
+}
+
+func (j *FileJournal) Save(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries[entry.ClientOrderId] = entry
+	content, err := json.MarshalIndent(j.sorted(), "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(j.name, content)
+}
+
+// The tolerance between the local clock and the exchange clock, in seconds.
+const clockSkew = 60
+
+type journalClient struct {
+	Client
+	journal OrderJournal
+	mu      sync.Mutex
+}
+
+// WithJournal returns a Client implementing OrderPlacer, which records the
+// orders with a ClientOrderId in journal, and fills the ClientOrderId of
+// the orders it returns.
+//
+// PlaceOrder with a client order id seen before never places a second order:
+// the order recorded is returned if known to be placed, and the request is
+// submitted again if it was turned down. Otherwise, as after a timeout, the
+// order is searched for among the open orders and the fills, as by
+// WithRetry, ignoring the orders recorded with other ids, and the request is
+// submitted again only if there is surely none. Reusing a client order id
+// for another request is an error.
+func WithJournal(c Client, journal OrderJournal) Client {
+	return &journalClient{Client: c, journal: journal}
+}
+
+func (j *journalClient) PlaceOrder(req OrderRequest) (order Order, err error) {
+	if req.ClientOrderId == "" {
+		return PlaceOrder(j.Client, req)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, ok, err := j.journal.Load(req.ClientOrderId)
+	if err != nil {
+		return
+	}
+	if ok && entry.Request != req {
+		return order, fmt.Errorf("%w: client order id %q used for another order", ErrInvalidOrder, req.ClientOrderId)
+	}
+	switch {
+	case ok && entry.Placed:
+		return j.placed(entry)
+	case ok && entry.Status != StatusRejected:
+		if order, err = j.find(entry); err != ErrOrderNotFound {
+			return
+		}
+	default:
+		entry = JournalEntry{ClientOrderId: req.ClientOrderId, Request: req, Submitted: time.Now().Unix()}
+		if err = j.journal.Save(entry); err != nil {
+			return
+		}
+	}
+
+	order, err = PlaceOrder(j.Client, req)
+	if err != nil {
+		if rejected(err) {
+			entry.Status = StatusRejected
+			if serr := j.journal.Save(entry); serr != nil {
+				return order, fmt.Errorf("%v, and not recorded: %v", err, serr)
+			}
+			return
+		}
+		// It may have landed nonetheless.
+		if o, ferr := j.find(entry); ferr == nil {
+			return o, nil
+		}
+		return
+	}
+	order.ClientOrderId = req.ClientOrderId
+	entry.Placed, entry.OrderId, entry.Status = true, order.Id, order.Status
+	err = j.journal.Save(entry)
+	return
+}
+
+// placed returns the current state of a placed order. The order recorded is
+// returned if it cannot be looked up.
+func (j *journalClient) placed(entry JournalEntry) (order Order, err error) {
+	recorded := entry.Request.Order(entry.OrderId)
+	recorded.Status, recorded.ClientOrderId = entry.Status, entry.ClientOrderId
+	if entry.Status == StatusFilled {
+		recorded.Remain = 0
+	}
+	if entry.OrderId == 0 {
+		return recorded, nil
+	}
+	if getter, ok := j.Client.(OrderGetter); ok {
+		order, err = getter.GetOrder(entry.OrderId)
+		if errors.Is(err, ErrOrderNotFound) {
+			return recorded, nil
+		}
+		order.ClientOrderId = entry.ClientOrderId
+		return
+	}
+	orders, err := j.Client.Orders()
+	if err != nil {
+		return
+	}
+	for _, o := range orders {
+		if o.Id == entry.OrderId {
+			o.ClientOrderId = entry.ClientOrderId
+			return o, nil
+		}
+	}
+	// Gone, filled or cancelled.
+	if recorded.Status == StatusOpen || recorded.Status == StatusPartial {
+		recorded.Status = StatusUnknown
+	}
+	return recorded, nil
+}
+
+// find searches for the order of entry, and records it. It returns
+// ErrOrderNotFound only if there is surely none.
+func (j *journalClient) find(entry JournalEntry) (order Order, err error) {
+	entries, err := j.journal.Entries()
+	if err != nil {
+		return
+	}
+	claimed := make(map[int64]bool)
+	for _, e := range entries {
+		if e.Placed && e.OrderId != 0 {
+			claimed[e.OrderId] = true
+		}
+	}
+	var fills func(pair Pair, since int64) ([]Fill, error)
+	if lister, ok := j.Client.(FillLister); ok {
+		fills = lister.Fills
+	}
+	if order, err = findPlaced(j.Client, fills, claimed, entry.Request, entry.Submitted); err != nil {
+		return
+	}
+	order.ClientOrderId = entry.ClientOrderId
+	entry.Placed, entry.OrderId, entry.Status = true, order.Id, order.Status
+	err = j.journal.Save(entry)
+	return
+}
+
+// Orders fills the ClientOrderId of the orders recorded in the journal.
+func (j *journalClient) Orders() (orders []Order, err error) {
+	if orders, err = j.Client.Orders(); err != nil {
+		return
+	}
+	entries, err := j.journal.Entries()
+	if err != nil {
+		return
+	}
+	ids := make(map[int64]string)
+	for _, e := range entries {
+		if e.Placed && e.OrderId != 0 {
+			ids[e.OrderId] = e.ClientOrderId
+		}
+	}
+	for i := range orders {
+		orders[i].ClientOrderId = ids[orders[i].Id]
+	}
+	return
+}
@@ -0,0 +1,126 @@
+package coincross
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errNoFunds = &ExchangeError{Method: "Trade", Message: "insufficient funds", Category: CategoryInsufficientFunds}
+
+// filledPlacer places every order as filled at once, without an id, as BTC-E.
+type filledPlacer struct {
+	*tradeClient
+}
+
+func (f filledPlacer) PlaceOrder(req OrderRequest) (Order, error) {
+	f.calls++
+	order := req.Order(0)
+	order.Remain, order.Status = 0, StatusFilled
+	return order, nil
+}
+
+func newJournalClient(place func(f *tradeClient, call int) (int64, error)) *tradeClient {
+	f := newTradeClient(place)
+	// Placed long before, by someone else.
+	f.orders[0].Timestamp = time.Now().Unix() - 3600
+	return f
+}
+
+var journalRequest = OrderRequest{Side: Buy, Pair: BTC_USD, Price: 100, Amount: 100, ClientOrderId: "a"}
+
+func TestJournalReplay(t *testing.T) {
+	f := newJournalClient(func(f *tradeClient, call int) (int64, error) {
+		f.orders = append(f.orders, journalRequest.Order(7))
+		return 7, nil
+	})
+	c := WithJournal(f, NewMemoryJournal()).(OrderPlacer)
+	for i := 0; i < 2; i++ {
+		order, err := c.PlaceOrder(journalRequest)
+		if order.Id != 7 || order.ClientOrderId != "a" || err != nil {
+			t.Errorf("%d: got %v, %v, want order 7", i, order, err)
+		}
+	}
+	if f.calls != 1 {
+		t.Errorf("placed %d times, want once", f.calls)
+	}
+	other := journalRequest
+	other.Amount = 1
+	if _, err := c.PlaceOrder(other); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("got %v for another request with the same id, want ErrInvalidOrder", err)
+	}
+}
+
+func TestJournalLost(t *testing.T) {
+	f := newJournalClient(func(f *tradeClient, call int) (int64, error) {
+		f.orders = append(f.orders, journalRequest.Order(7))
+		return -1, errTimeout
+	})
+	c := WithJournal(f, NewMemoryJournal()).(OrderPlacer)
+	for i := 0; i < 2; i++ {
+		order, err := c.PlaceOrder(journalRequest)
+		if order.Id != 7 || err != nil {
+			t.Errorf("%d: got %v, %v, want order 7", i, order, err)
+		}
+	}
+	if f.calls != 1 {
+		t.Errorf("placed %d times, want once", f.calls)
+	}
+}
+
+func TestJournalRejected(t *testing.T) {
+	f := newJournalClient(func(f *tradeClient, call int) (int64, error) {
+		if call == 1 {
+			return -1, errNoFunds
+		}
+		f.orders = append(f.orders, journalRequest.Order(8))
+		return 8, nil
+	})
+	// An order just placed by someone else, which matches the request.
+	f.orders[0].Timestamp = time.Now().Unix()
+	journal := NewMemoryJournal()
+	c := WithJournal(f, journal).(OrderPlacer)
+	if order, err := c.PlaceOrder(journalRequest); err != errNoFunds {
+		t.Fatalf("got %v, %v, want %v", order, err, errNoFunds)
+	}
+	if entry, _, _ := journal.Load("a"); entry.Placed || entry.Status != StatusRejected {
+		t.Errorf("recorded %+v, want rejected", entry)
+	}
+	// Turned down, so it is submitted again.
+	if order, err := c.PlaceOrder(journalRequest); order.Id != 8 || err != nil {
+		t.Errorf("got %v, %v, want order 8", order, err)
+	}
+	if f.calls != 2 {
+		t.Errorf("placed %d times, want twice", f.calls)
+	}
+}
+
+func TestJournalFilledAtOnce(t *testing.T) {
+	f := filledPlacer{newJournalClient(nil)}
+	c := WithJournal(f, NewMemoryJournal()).(OrderPlacer)
+	for i := 0; i < 2; i++ {
+		order, err := c.PlaceOrder(journalRequest)
+		if order.Status != StatusFilled || order.ClientOrderId != "a" || err != nil {
+			t.Errorf("%d: got %v, %v, want filled", i, order, err)
+		}
+	}
+	if f.calls != 1 {
+		t.Errorf("placed %d times, want once", f.calls)
+	}
+}
+
+// An order filled at once, whose reply was lost before a restart.
+func TestJournalFilledBeforeRestart(t *testing.T) {
+	f := newJournalClient(nil)
+	now := time.Now().Unix()
+	f.fills = []Fill{{OrderId: 9, Side: Buy, Pair: BTC_USD, Price: 100, Amount: 100, Timestamp: now}}
+	journal := NewMemoryJournal()
+	journal.Save(JournalEntry{ClientOrderId: "a", Request: journalRequest, Submitted: now})
+	order, err := WithJournal(f, journal).(OrderPlacer).PlaceOrder(journalRequest)
+	if order.Id != 9 || order.Status != StatusFilled || err != nil {
+		t.Errorf("got %v, %v, want order 9 filled", order, err)
+	}
+	if f.calls != 0 {
+		t.Errorf("placed %d times, want none", f.calls)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package coincross
+
+import (
+	"os"
+)
+
+// File locking is not supported, FileNonce and FileJournal are only safe
+// within a process.
+
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,130 @@
+package coincross
+
+// MarketInfo describes the trading rules of a pair on an exchange.
+// Zero values mean there is no such restriction.
+type MarketInfo struct {
+	Pair Pair
+	// The price and amount of an order should be multiples of these.
+	TickSize, LotSize  Decimal
+	MinPrice, MaxPrice Decimal
+	MinAmount          Decimal
+	// The trading fee as a fraction, i.e. 0.002 for 0.2%.
+	Fee Decimal
+}
+
+// MarketLister is implemented by clients that can describe their markets.
+type MarketLister interface {
+	// Returns the markets available on the exchange.
+	Markets() ([]MarketInfo, error)
+}
+
+// FindMarket returns the MarketInfo of the given pair.
+func FindMarket(markets []MarketInfo, pair Pair) (MarketInfo, bool) {
+	for _, m := range markets {
+		if m.Pair == pair {
+			return m, true
+		}
+	}
+	return MarketInfo{}, false
+}
+
+// ValidationReason tells why an order breaks the market rules.
+type ValidationReason int
+
+const (
+	_ ValidationReason = iota
+	UnknownPair
+	BelowMinAmount
+	PriceOutOfRange
+	BadPrecision
+)
+
+// ValidationError is returned when an order breaks the market rules.
+type ValidationError struct {
+	Reason ValidationReason
+	Pair   Pair
+	// Field is either "price" or "amount".
+	Field string
+	// The offending value and the limit it breaks.
+	Value, Limit Decimal
+}
+
+// Is makes ValidationError match ErrInvalidOrder.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidOrder
+}
+
+// Validate checks an order against the market rules.
+func (m MarketInfo) Validate(tradeType TradeType, price, amount Decimal) error {
+	invalid := func(reason ValidationReason, field string, value, limit Decimal) error {
+		return &ValidationError{reason, m.Pair, field, value, limit}
+	}
+	switch {
+	case m.TickSize > 0 && price.Floor(m.TickSize) != price:
+		return invalid(BadPrecision, "price", price, m.TickSize)
+	case m.LotSize > 0 && amount.Floor(m.LotSize) != amount:
+		return invalid(BadPrecision, "amount", amount, m.LotSize)
+	case amount <= 0 || amount < m.MinAmount:
+		return invalid(BelowMinAmount, "amount", amount, m.MinAmount)
+	case price <= 0 || price < m.MinPrice:
+		return invalid(PriceOutOfRange, "price", price, m.MinPrice)
+	case m.MaxPrice > 0 && price > m.MaxPrice:
+		return invalid(PriceOutOfRange, "price", price, m.MaxPrice)
+	}
+	return nil
+}
+
+// Normalize rounds an order to the market precision, and then validates it.
+// Buy prices are rounded down and sell prices up, so the price is never worse
+// than asked. Amounts are always rounded down.
+func (m MarketInfo) Normalize(tradeType TradeType, price, amount Decimal) (order Order, err error) {
+	if tradeType == Sell {
+		price = price.Ceil(m.TickSize)
+	} else {
+		price = price.Floor(m.TickSize)
+	}
+	amount = amount.Floor(m.LotSize)
+	order = Order{Type: tradeType, Pair: m.Pair, Price: price, Remain: amount, Amount: amount}
+	err = m.Validate(tradeType, price, amount)
+	return
+}
+
+// ValidateTrade checks the arguments of Client.Trade against the market rules.
+// Nothing is checked if c is not a MarketLister.
+func ValidateTrade(c Client, tradeType TradeType, pair Pair, price, amount Decimal) error {
+	m, err := clientMarket(c, pair)
+	if err != nil || m == nil {
+		return err
+	}
+	return m.Validate(tradeType, price, amount)
+}
+
+// NormalizeTrade is like MarketInfo.Normalize, with the market looked up from c.
+// The order is only validated to be positive if c is not a MarketLister.
+func NormalizeTrade(c Client, tradeType TradeType, pair Pair, price, amount Decimal) (Order, error) {
+	m, err := clientMarket(c, pair)
+	if err != nil {
+		return Order{}, err
+	}
+	if m == nil {
+		m = &MarketInfo{Pair: pair}
+	}
+	return m.Normalize(tradeType, price, amount)
+}
+
+// clientMarket returns the market of pair, or nil if c is not a MarketLister.
+func clientMarket(c Client, pair Pair) (*MarketInfo, error) {
+	lister, ok := c.(MarketLister)
+	if !ok {
+		return nil, nil
+	}
+	markets, err := lister.Markets()
+	if err != nil {
+		return nil, err
+	}
+	m, ok := FindMarket(markets, pair)
+	if !ok {
+		return nil, &ValidationError{Reason: UnknownPair, Pair: pair}
+	}
+	return &m, nil
+}
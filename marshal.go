@@ -1,8 +1,10 @@
 package coincross
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -41,19 +43,185 @@ func (p *Pair) UnmarshalJSON(b []byte) (err error) {
 }
 
 func (o Order) String() string {
-	return fmt.Sprintf("%s\t%d\t%s\t%s\t%f\t%f(%f)", time.Unix(o.Timestamp, 0).Format("20060102 15:04:05"), o.Id, o.Type, o.Pair, o.Price, o.Remain, o.Amount)
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s\t%s(%s)\t%s", time.Unix(o.Timestamp, 0).Format("20060102 15:04:05"), o.Id, o.Type, o.Pair, o.Price, o.Remain, o.Amount, o.Status)
+}
+
+var orderStatuses = map[OrderStatus]string{
+	StatusUnknown:   "unknown",
+	StatusOpen:      "open",
+	StatusPartial:   "partial",
+	StatusFilled:    "filled",
+	StatusCancelled: "cancelled",
+	StatusRejected:  "rejected",
+}
+
+func (s OrderStatus) String() string {
+	return orderStatuses[s]
 }
 
 func (t Trade) String() string {
-	return fmt.Sprintf("%s %d\t%s\t%8.3f@%-8.6g\t!%s", t.Pair, t.Id, t.Type, t.Amount, t.Price, time.Unix(t.Timestamp, 0).Format("15:04:05"))
+	return fmt.Sprintf("%s %d\t%s\t%8.3f@%-8.6g\t!%s", t.Pair, t.Id, t.Type, t.Amount.Float64(), t.Price.Float64(), time.Unix(t.Timestamp, 0).Format("15:04:05"))
+}
+
+func (t TaggedTrade) String() string {
+	return t.Exchange + " " + t.Trade.String()
+}
+
+var orderEventTypes = map[OrderEventType]string{
+	OrderPlaced:          "placed",
+	OrderPartiallyFilled: "partially filled",
+	OrderFilled:          "filled",
+	OrderCancelled:       "cancelled",
+	OrderDisappeared:     "disappeared",
+}
+
+func (t OrderEventType) String() string {
+	return orderEventTypes[t]
+}
+
+func (e OrderEvent) String() string {
+	return fmt.Sprintf("%s\t%s\tfilled %s", e.Type, e.Order, e.Filled)
+}
+
+func (f Fill) String() string {
+	return fmt.Sprintf("%s\t%d\t%d\t%s\t%s\t%s@%s\tfee:%s%s\t%s", time.Unix(f.Timestamp, 0).Format("20060102 15:04:05"), f.TradeId, f.OrderId, f.Side, f.Pair, f.Amount, f.Price, f.Fee, f.FeeSymbol, f.Liquidity)
+}
+
+func (l Liquidity) String() string {
+	switch l {
+	case Maker:
+		return "maker"
+	case Taker:
+		return "taker"
+	default:
+		return "unknown"
+	}
+}
+
+func (g Gap) String() string {
+	return fmt.Sprintf("%s gap between %d@%s and %d@%s", g.Pair, g.After.Id, time.Unix(g.After.Timestamp, 0).Format("15:04:05"), g.Before.Id, time.Unix(g.Before.Timestamp, 0).Format("15:04:05"))
 }
 
 func (t Transaction) String() string {
 	amounts := ""
 	for k, v := range t.Amounts {
-		amounts = amounts + fmt.Sprintf("\t%s:%f", k, v)
+		amounts = amounts + fmt.Sprintf("\t%s:%s", k, v)
+	}
+	return fmt.Sprintf("%s\t%d\t%s%s\t%s", time.Unix(t.Timestamp, 0).Format("20060102 15:04:05"), t.Id, t.Kind, amounts, t.Descritpion)
+}
+
+var transactionKinds = map[TransactionKind]string{
+	TransactionUnknown:        "unknown",
+	TransactionDeposit:        "deposit",
+	TransactionWithdrawal:     "withdrawal",
+	TransactionBuy:            "buy",
+	TransactionSell:           "sell",
+	TransactionFee:            "fee",
+	TransactionOrderPlaced:    "placed",
+	TransactionOrderCancelled: "cancelled",
+}
+
+func (k TransactionKind) String() string {
+	return transactionKinds[k]
+}
+
+func (m MarketInfo) String() string {
+	return fmt.Sprintf("%s\ttick:%s\tlot:%s\tprice:%s-%s\tmin:%s\tfee:%s", m.Pair, m.TickSize, m.LotSize, m.MinPrice, m.MaxPrice, m.MinAmount, m.Fee)
+}
+
+func (t OrderType) String() string {
+	switch t {
+	case LimitOrder:
+		return "limit"
+	case MarketOrder:
+		return "market"
+	default:
+		return ""
+	}
+}
+
+func (t TimeInForce) String() string {
+	switch t {
+	case GTC:
+		return "GTC"
+	case IOC:
+		return "IOC"
+	case FOK:
+		return "FOK"
+	case PostOnly:
+		return "post-only"
+	default:
+		return ""
+	}
+}
+
+// Set parses a TimeInForce from its String, case insensitively.
+func (t *TimeInForce) Set(s string) error {
+	for tif := GTC; tif <= PostOnly; tif++ {
+		if strings.EqualFold(s, tif.String()) {
+			*t = tif
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown time in force: %q", s)
+}
+
+func (c EndpointClass) String() string {
+	switch c {
+	case Public:
+		return "public"
+	case Private:
+		return "private"
+	default:
+		return ""
+	}
+}
+
+func (c ErrorCategory) String() string {
+	switch c {
+	case CategoryAuth:
+		return "auth"
+	case CategoryPermission:
+		return "permission"
+	case CategoryInsufficientFunds:
+		return "insufficient funds"
+	case CategoryRateLimited:
+		return "rate limited"
+	case CategoryNonce:
+		return "nonce"
+	case CategoryInvalidOrder:
+		return "invalid order"
+	case CategoryOrderNotFound:
+		return "order not found"
+	case CategoryTemporary:
+		return "temporary"
+	case CategoryUnsupported:
+		return "unsupported"
+	default:
+		return "unknown"
+	}
+}
+
+func (r ValidationReason) String() string {
+	switch r {
+	case UnknownPair:
+		return "unknown pair"
+	case BelowMinAmount:
+		return "below min amount"
+	case PriceOutOfRange:
+		return "price out of range"
+	case BadPrecision:
+		return "bad precision"
+	default:
+		return ""
 	}
-	return fmt.Sprintf("%s\t%d%s\t%s", time.Unix(t.Timestamp, 0).Format("20060102 15:04:05"), t.Id, amounts, t.Descritpion)
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid order on %s: %s", e.Pair, e.Reason)
+	}
+	return fmt.Sprintf("invalid order on %s: %s: %s %s (limit %s)", e.Pair, e.Reason, e.Field, e.Value, e.Limit)
 }
 
 func (t *TradeType) MarshalJSON() ([]byte, error) {
@@ -94,5 +262,48 @@ func (t TradeType) String() string {
 }
 
 func (t *TradeType) Set(s string) error {
-	return t.UnmarshalJSON([]byte(s))
+	return t.UnmarshalJSON([]byte(strconv.Quote(s)))
+}
+
+// String returns the shortest exact representation of d, such as "0.01".
+func (d Decimal) String() string {
+	sign := ""
+	u := uint64(d)
+	if d < 0 {
+		sign = "-"
+		u = -u
+	}
+	s := fmt.Sprintf("%s%d.%08d", sign, u/decimalScale, u%decimalScale)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON encodes d as a JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON decodes d from a JSON number or a quoted number,
+// as some exchanges send numbers as strings.
+func (d *Decimal) UnmarshalJSON(b []byte) (err error) {
+	b = bytes.TrimSpace(b)
+	if string(b) == "null" {
+		return nil
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err = json.Unmarshal(b, &s); err != nil {
+			return
+		}
+		if s == "" {
+			*d = 0
+			return nil
+		}
+	}
+	*d, err = ParseDecimal(s)
+	return
+}
+
+func (d *Decimal) Set(s string) (err error) {
+	*d, err = ParseDecimal(s)
+	return
 }
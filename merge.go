@@ -0,0 +1,288 @@
+package coincross
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TaggedTrade is a trade of a merged stream, tagged with its exchange.
+type TaggedTrade struct {
+	// The registered name of the exchange, such as "btce".
+	Exchange string
+	Trade
+}
+
+// StreamSource is a stream of trades to merge.
+type StreamSource struct {
+	// Exchange tags the trades of the source.
+	Exchange string
+	Client   Client
+	Pair     Pair
+	// Since is given to Client.Stream.
+	Since int64
+}
+
+// MergeOptions configures MergeStreams.
+type MergeOptions struct {
+	// Lateness is how long a trade is held back, waiting for the earlier
+	// trades from the other sources. Trades arriving later than that are
+	// delivered at once, out of order.
+	Lateness time.Duration
+}
+
+// ErrDuplicateSource is returned by MergedStream.Add for a source of the
+// same exchange and pair as an existing one.
+var ErrDuplicateSource = errors.New("duplicate stream source")
+
+var errMergedStreamClosed = errors.New("merged stream closed")
+
+type sourceKey struct {
+	exchange string
+	pair     Pair
+}
+
+type mergeSource struct {
+	streamer *Streamer
+	removed  chan struct{}
+}
+
+// MergedStream merges the trade streams of several sources into one,
+// ordered by timestamp.
+type MergedStream struct {
+	// C delivers the trades. It is closed when the stream is closed.
+	C <-chan TaggedTrade
+
+	opts    MergeOptions
+	in      chan *heldTrade
+	errors  chan error
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	sources map[sourceKey]*mergeSource
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// MergeStreams starts a MergedStream of the sources. More sources can be
+// added and removed while it runs.
+func MergeStreams(opts MergeOptions, sources ...StreamSource) (*MergedStream, error) {
+	trades := make(chan TaggedTrade, 100)
+	m := &MergedStream{
+		C:       trades,
+		opts:    opts,
+		in:      make(chan *heldTrade),
+		errors:  make(chan error, 16),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		sources: make(map[sourceKey]*mergeSource),
+	}
+	go m.run(trades)
+	for _, source := range sources {
+		if err := m.Add(source); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Add starts following a source.
+func (m *MergedStream) Add(source StreamSource) error {
+	key := sourceKey{source.Exchange, source.Pair}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return errMergedStreamClosed
+	}
+	if _, ok := m.sources[key]; ok {
+		return fmt.Errorf("%w: %s %s", ErrDuplicateSource, source.Exchange, source.Pair)
+	}
+	src := &mergeSource{source.Client.Stream(source.Pair, source.Since), make(chan struct{})}
+	m.sources[key] = src
+	m.wg.Add(1)
+	go m.forward(key, src)
+	return nil
+}
+
+// Remove stops following the source of the exchange and pair, and tells
+// whether there was one. The trades of the source already received may
+// still be delivered.
+func (m *MergedStream) Remove(exchange string, pair Pair) bool {
+	key := sourceKey{exchange, pair}
+	m.mu.Lock()
+	src, ok := m.sources[key]
+	delete(m.sources, key)
+	m.mu.Unlock()
+	if ok {
+		close(src.removed)
+		src.streamer.Close()
+	}
+	return ok
+}
+
+// Errors delivers the errors of the sources. A source stopped by an error
+// is removed. Errors not received in time are dropped.
+// The channel is closed when the stream is closed.
+func (m *MergedStream) Errors() <-chan error {
+	return m.errors
+}
+
+// Close stops all the sources, and waits until the stream has stopped.
+// The trades held back are dropped. It is safe to call Close more than once.
+func (m *MergedStream) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	sources := m.sources
+	m.sources = make(map[sourceKey]*mergeSource)
+	m.mu.Unlock()
+	for _, src := range sources {
+		close(src.removed)
+		src.streamer.Close()
+	}
+	m.once.Do(func() { close(m.closing) })
+	<-m.done
+	return nil
+}
+
+// report sends an error, dropping it if nobody is listening.
+func (m *MergedStream) report(err error) {
+	select {
+	case m.errors <- err:
+	default:
+	}
+}
+
+// forward feeds the trades of a source to run.
+func (m *MergedStream) forward(key sourceKey, src *mergeSource) {
+	defer m.wg.Done()
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for err := range src.streamer.Errors() {
+			m.report(fmt.Errorf("%s %s: %w", key.exchange, key.pair, err))
+		}
+	}()
+	for t := range src.streamer.C {
+		select {
+		case m.in <- &heldTrade{trade: TaggedTrade{key.exchange, t}}:
+		case <-src.removed:
+			return
+		}
+	}
+	if err := src.streamer.Err(); err != nil {
+		m.report(fmt.Errorf("%s %s: %w", key.exchange, key.pair, err))
+		m.mu.Lock()
+		if m.sources[key] == src {
+			delete(m.sources, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// run holds the trades back for Lateness, and delivers them in order.
+//
+// When a trade has been held for Lateness, it and all the held trades not
+// later than it are delivered.
+func (m *MergedStream) run(trades chan<- TaggedTrade) {
+	defer close(m.done)
+	defer close(m.errors)
+	defer close(trades)
+
+	var (
+		held    tradeHeap
+		arrived []*heldTrade
+		ready   []TaggedTrade
+		mark    int64
+		timer   = time.NewTimer(0)
+		timeout <-chan time.Time
+	)
+	defer timer.Stop()
+	<-timer.C
+
+	for {
+		var (
+			first   TaggedTrade
+			updates chan<- TaggedTrade
+		)
+		if len(ready) > 0 {
+			first, updates = ready[0], trades
+		}
+
+		select {
+		case t := <-m.in:
+			t.arrival = time.Now()
+			heap.Push(&held, t)
+			arrived = append(arrived, t)
+		case <-timeout:
+			timeout = nil
+		case updates <- first:
+			ready = ready[1:]
+			continue
+		case <-m.closing:
+			// No source is added once closing, and the ones left are
+			// stopped, so the forwarders return soon.
+			m.wg.Wait()
+			return
+		}
+
+		now := time.Now()
+		for len(arrived) > 0 && now.Sub(arrived[0].arrival) >= m.opts.Lateness {
+			if !arrived[0].delivered && arrived[0].trade.Timestamp > mark {
+				mark = arrived[0].trade.Timestamp
+			}
+			arrived = arrived[1:]
+		}
+		for len(held) > 0 && held[0].trade.Timestamp <= mark {
+			t := heap.Pop(&held).(*heldTrade)
+			t.delivered = true
+			ready = append(ready, t.trade)
+		}
+		for len(arrived) > 0 && arrived[0].delivered {
+			arrived = arrived[1:]
+		}
+		if timeout == nil && len(arrived) > 0 {
+			timer.Reset(m.opts.Lateness - now.Sub(arrived[0].arrival))
+			timeout = timer.C
+		}
+	}
+}
+
+type heldTrade struct {
+	trade     TaggedTrade
+	arrival   time.Time
+	delivered bool
+}
+
+// tradeHeap orders the trades by timestamp, then by exchange, pair and id.
+type tradeHeap []*heldTrade
+
+func (h tradeHeap) Len() int { return len(h) }
+
+func (h tradeHeap) Less(i, j int) bool {
+	a, b := h[i].trade, h[j].trade
+	switch {
+	case a.Timestamp != b.Timestamp:
+		return a.Timestamp < b.Timestamp
+	case a.Exchange != b.Exchange:
+		return a.Exchange < b.Exchange
+	case a.Pair != b.Pair:
+		return a.Pair.String() < b.Pair.String()
+	}
+	return a.Id < b.Id
+}
+
+func (h tradeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *tradeHeap) Push(x interface{}) { *h = append(*h, x.(*heldTrade)) }
+
+func (h *tradeHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,147 @@
+package coincross
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NonceSource generates the nonces for signed requests.
+// Nonces of the same key must be strictly increasing.
+type NonceSource interface {
+	Nonce(key string) (int64, error)
+}
+
+// UnixSeconds is a clock for exchanges using seconds as nonces.
+func UnixSeconds() int64 {
+	return time.Now().Unix()
+}
+
+// UnixMicros is a clock for exchanges using microseconds as nonces.
+func UnixMicros() int64 {
+	return time.Now().UnixNano() / 1000
+}
+
+// nextNonce returns a nonce greater than last, and not less than now.
+func nextNonce(last, now int64) int64 {
+	if now > last {
+		return now
+	}
+	return last + 1
+}
+
+// NonceCounter is an in-memory NonceSource. It is safe for concurrent use.
+type NonceCounter struct {
+	clock func() int64
+	mu    sync.Mutex
+	last  map[string]int64
+}
+
+// NewNonceCounter returns a NonceCounter. The nonces never fall behind clock,
+// but they run ahead of it after a burst of requests, so a restarted process
+// may reuse them. Use FileNonce to keep them increasing across restarts.
+func NewNonceCounter(clock func() int64) *NonceCounter {
+	return &NonceCounter{clock: clock, last: make(map[string]int64)}
+}
+
+func (c *NonceCounter) Nonce(key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nonce := nextNonce(c.last[key], c.clock())
+	c.last[key] = nonce
+	return nonce, nil
+}
+
+// FileNonce is a NonceSource that saves the last nonce of each key in a
+// directory, so the nonces keep increasing across restarts. It is safe for
+// concurrent use, and the files are locked so that processes sharing the
+// directory do not clash, where the platform supports file locking.
+type FileNonce struct {
+	dir   string
+	clock func() int64
+	mu    sync.Mutex
+}
+
+// NewFileNonce returns a FileNonce saving to dir, which must exist.
+func NewFileNonce(dir string, clock func() int64) *FileNonce {
+	return &FileNonce{dir: dir, clock: clock}
+}
+
+func (f *FileNonce) Nonce(key string) (nonce int64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Keys are usually API keys, so they are not used as file names directly.
+	sum := sha1.Sum([]byte(key))
+	name := filepath.Join(f.dir, "nonce-"+hex.EncodeToString(sum[:]))
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	if err = lockFile(file); err != nil {
+		return
+	}
+	defer unlockFile(file)
+
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return
+	}
+	last, _ := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	nonce = nextNonce(last, f.clock())
+	if err = file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(strconv.FormatInt(nonce, 10)), 0)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	return
+}
+
+var (
+	keyLocksMu sync.Mutex
+	keyLocks   = make(map[string]*KeyMutex)
+)
+
+// KeyMutex is a mutual exclusion lock which can be given up while waiting.
+type KeyMutex struct {
+	ch chan struct{}
+}
+
+// Lock waits for the lock, or returns the error of ctx if it is done first.
+func (m *KeyMutex) Lock(ctx context.Context) error {
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock releases the lock taken by Lock.
+func (m *KeyMutex) Unlock() {
+	<-m.ch
+}
+
+// KeyLock returns a lock shared by the clients of the exchange and key in
+// the process, for the exchanges which need some signed requests of a key to
+// be made one at a time.
+func KeyLock(exchange, key string) *KeyMutex {
+	keyLocksMu.Lock()
+	defer keyLocksMu.Unlock()
+	name := exchange + "\x00" + key
+	m, ok := keyLocks[name]
+	if !ok {
+		m = &KeyMutex{ch: make(chan struct{}, 1)}
+		keyLocks[name] = m
+	}
+	return m
+}
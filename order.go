@@ -0,0 +1,150 @@
+package coincross
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OrderType is the type of an OrderRequest.
+type OrderType int
+
+const (
+	LimitOrder OrderType = iota
+	// A market order is filled at the best prices available.
+	MarketOrder
+)
+
+// TimeInForce tells how long an order stays open.
+type TimeInForce int
+
+const (
+	// Good till cancelled.
+	GTC TimeInForce = iota
+	// Immediate or cancel: the part not filled at once is cancelled.
+	IOC
+	// Fill or kill: the order is either filled at once, or cancelled.
+	FOK
+	// The order is cancelled instead of being filled at once, so that it
+	// only ever makes liquidity.
+	PostOnly
+)
+
+// OrderRequest describes an order to place.
+type OrderRequest struct {
+	Side TradeType
+	Pair Pair
+	Type OrderType
+	// The limit price, not used by market orders.
+	Price       Decimal
+	Amount      Decimal
+	TimeInForce TimeInForce
+	// An id chosen by the caller. Neither exchange takes one, so it is only
+	// kept locally. See WithJournal.
+	ClientOrderId string
+}
+
+// OrderPlacer is implemented by clients that can place any OrderRequest,
+// either natively or by emulation.
+type OrderPlacer interface {
+	// PlaceOrder places the order, and returns its state after placement.
+	// It returns an error matching ErrUnsupportedOrder if the request cannot
+	// be carried out.
+	PlaceOrder(req OrderRequest) (Order, error)
+}
+
+// Check tells whether the request is well formed.
+func (r OrderRequest) Check() error {
+	switch {
+	case r.Side != Buy && r.Side != Sell:
+		return fmt.Errorf("%w: unknown side %d", ErrInvalidOrder, r.Side)
+	case r.Type != LimitOrder && r.Type != MarketOrder:
+		return fmt.Errorf("%w: unknown order type %d", ErrInvalidOrder, r.Type)
+	case r.TimeInForce < GTC || r.TimeInForce > PostOnly:
+		return fmt.Errorf("%w: unknown time in force %d", ErrInvalidOrder, r.TimeInForce)
+	case r.Amount <= 0:
+		return fmt.Errorf("%w: amount %s is not positive", ErrInvalidOrder, r.Amount)
+	case r.Type == LimitOrder && r.Price <= 0:
+		return fmt.Errorf("%w: price %s is not positive", ErrInvalidOrder, r.Price)
+	}
+	return nil
+}
+
+// UnsupportedOrder returns an error matching ErrUnsupportedOrder, for an
+// order request the exchange cannot carry out. The exchange may be empty if
+// not known.
+func UnsupportedOrder(exchange, format string, a ...interface{}) error {
+	return &ExchangeError{
+		Exchange: exchange,
+		Method:   "PlaceOrder",
+		Message:  fmt.Sprintf(format, a...),
+		Category: CategoryUnsupported,
+	}
+}
+
+// PlaceOrder places the order with c.PlaceOrder if c implements OrderPlacer.
+// Otherwise limit orders are placed with c.Trade, with IOC emulated by
+// CancelRemainder, and the others are rejected with ErrUnsupportedOrder.
+func PlaceOrder(c Client, req OrderRequest) (Order, error) {
+	if p, ok := c.(OrderPlacer); ok {
+		return p.PlaceOrder(req)
+	}
+	if err := req.Check(); err != nil {
+		return Order{}, err
+	}
+	if req.Type != LimitOrder {
+		return Order{}, UnsupportedOrder("", "%s orders not supported", req.Type)
+	}
+	if req.TimeInForce != GTC && req.TimeInForce != IOC {
+		return Order{}, UnsupportedOrder("", "%s orders not supported", req.TimeInForce)
+	}
+	orderId, err := c.Trade(req.Side, req.Pair, req.Price, req.Amount)
+	if err != nil {
+		return Order{}, err
+	}
+	order := req.Order(orderId)
+	if req.TimeInForce == IOC {
+		return CancelRemainder(c, order)
+	}
+	return order, nil
+}
+
+// Order returns the order just placed for the request, as far as it is known.
+func (r OrderRequest) Order(orderId int64) Order {
+	return Order{
+		Id:     orderId,
+		Type:   r.Side,
+		Price:  r.Price,
+		Remain: r.Amount,
+		Amount: r.Amount,
+		Pair:   r.Pair,
+		Status: StatusOpen,
+	}
+}
+
+// CancelRemainder cancels what is left of a just placed order, as for IOC,
+// and returns its final state if c implements OrderGetter. Otherwise the
+// status is StatusCancelled only if Cancel reports success, and
+// StatusUnknown if the order may have been filled. An order already filled
+// or gone is not an error.
+func CancelRemainder(c Client, order Order) (Order, error) {
+	if order.Status == StatusFilled {
+		return order, nil
+	}
+	cancelled, err := c.Cancel(order.Id)
+	if err != nil && !errors.Is(err, ErrOrderNotFound) {
+		return order, err
+	}
+	if getter, ok := c.(OrderGetter); ok {
+		if final, gerr := getter.GetOrder(order.Id); gerr == nil {
+			return final, nil
+		}
+	}
+	if cancelled && err == nil {
+		order.Status = StatusCancelled
+	} else {
+		// Filled or cancelled meanwhile, or not cancelled for a reason
+		// not told.
+		order.Status = StatusUnknown
+	}
+	return order, nil
+}
@@ -0,0 +1,181 @@
+package coincross
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// OrderEventType is the type of an OrderEvent.
+type OrderEventType int
+
+const (
+	// The order is seen for the first time.
+	OrderPlaced OrderEventType = iota
+	OrderPartiallyFilled
+	OrderFilled
+	// The order is gone before being filled, possibly after partial fills.
+	OrderCancelled
+	// The order is gone, and it is unknown whether it was filled or
+	// cancelled, as it cannot be looked up.
+	OrderDisappeared
+)
+
+// OrderEvent is a change of an order.
+type OrderEvent struct {
+	Type OrderEventType
+	// The last state of the order, with the Amount first seen.
+	Order Order
+	// The total amount filled so far, including the fills made before the
+	// order was first seen, as far as known. 0 for OrderPlaced and
+	// OrderDisappeared.
+	Filled Decimal
+}
+
+// OrderWatcher follows the active orders of a client, until it is closed or
+// fails.
+type OrderWatcher struct {
+	// C delivers the events. It is closed when the watcher stops.
+	C <-chan OrderEvent
+
+	stream
+}
+
+// WatchOrders polls Client.Orders, and reports the changes of the orders,
+// until ctx is done or the watcher is closed. The orders active at the first
+// poll are reported as placed. Failed polls are reported to Errors, and
+// retried with backoff.
+//
+// The fills are found by the decrease of Order.Remain. When an order is gone,
+// its final state is looked up if c implements OrderGetter. Otherwise its
+// fills are looked up if c implements FillLister, and tells the order of
+// the fills: the order is filled if they add up to the Amount first seen.
+// Note that some exchanges, such as BTC-E, report the remaining amount as
+// Amount, so an order partially filled before the first poll may be
+// reported as filled when cancelled.
+func WatchOrders(ctx context.Context, c Client, opts PollOptions) *OrderWatcher {
+	events := make(chan OrderEvent, 100)
+	w := &OrderWatcher{C: events, stream: newStream()}
+	go w.run(ctx, c, opts, events)
+	return w
+}
+
+func (w *OrderWatcher) run(ctx context.Context, c Client, opts PollOptions, events chan<- OrderEvent) {
+	cc := AsContextClient(c)
+	known := make(map[int64]Order)
+	err := w.poll(ctx, opts, func(ctx context.Context) error {
+		orders, err := cc.OrdersContext(ctx)
+		if err != nil {
+			return err
+		}
+		for _, e := range w.diff(ctx, c, known, orders) {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+	close(events)
+	w.finish(err)
+}
+
+// diff updates known to orders, and returns the changes.
+func (w *OrderWatcher) diff(ctx context.Context, c Client, known map[int64]Order, orders []Order) (events []OrderEvent) {
+	orders = append([]Order(nil), orders...)
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id < orders[j].Id })
+	active := make(map[int64]bool)
+	for _, o := range orders {
+		active[o.Id] = true
+		last, ok := known[o.Id]
+		if !ok {
+			known[o.Id] = o
+			events = append(events, OrderEvent{OrderPlaced, o, 0})
+			if o.Remain < o.Amount {
+				events = append(events, OrderEvent{OrderPartiallyFilled, o, o.Amount - o.Remain})
+			}
+			continue
+		}
+		o.Amount = last.Amount
+		known[o.Id] = o
+		if o.Remain < last.Remain {
+			events = append(events, OrderEvent{OrderPartiallyFilled, o, o.Amount - o.Remain})
+		}
+	}
+
+	var gone []Order
+	for id, o := range known {
+		if !active[id] {
+			gone = append(gone, o)
+			delete(known, id)
+		}
+	}
+	sort.Slice(gone, func(i, j int) bool { return gone[i].Id < gone[j].Id })
+
+	getter, canGet := c.(OrderGetter)
+	lister, canList := c.(FillLister)
+	fills := make(map[Pair]map[int64][]Fill)
+	for _, o := range gone {
+		event := OrderEvent{OrderDisappeared, o, 0}
+		if canGet {
+			final, err := getter.GetOrder(o.Id)
+			if err == nil && (final.Status == StatusFilled || final.Status == StatusCancelled) {
+				event.Order = final
+				event.Filled = final.Amount - final.Remain
+				event.Type = OrderCancelled
+				if final.Status == StatusFilled {
+					event.Type, event.Filled = OrderFilled, final.Amount
+				}
+				events = append(events, event)
+				continue
+			}
+			if err != nil {
+				w.report(err)
+			}
+		}
+		if canList {
+			byOrder, fetched := fills[o.Pair]
+			if !fetched {
+				byOrder = w.fillsByOrder(ctx, lister, o.Pair, gone)
+				fills[o.Pair] = byOrder
+			}
+			if byOrder != nil {
+				event.Type = OrderCancelled
+				for _, f := range byOrder[o.Id] {
+					event.Filled += f.Amount
+				}
+				if event.Filled >= o.Amount {
+					event.Type = OrderFilled
+				}
+			}
+		}
+		events = append(events, event)
+	}
+	return
+}
+
+// fillsByOrder returns the fills of the pair since the oldest of the orders
+// was placed, by order id, or nil if they cannot be listed, or are not all
+// attributed to orders, as on BTCChina.
+func (w *OrderWatcher) fillsByOrder(ctx context.Context, lister FillLister, pair Pair, orders []Order) map[int64][]Fill {
+	since := time.Now().Unix()
+	for _, o := range orders {
+		if o.Pair == pair && o.Timestamp < since {
+			since = o.Timestamp
+		}
+	}
+	fills, err := FillsContext(ctx, lister, pair, since-clockSkew)
+	if err != nil {
+		w.report(err)
+		return nil
+	}
+	byOrder := make(map[int64][]Fill)
+	for _, f := range fills {
+		if f.OrderId == 0 {
+			return nil
+		}
+		byOrder[f.OrderId] = append(byOrder[f.OrderId], f)
+	}
+	return byOrder
+}
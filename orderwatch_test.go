@@ -0,0 +1,65 @@
+package coincross
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// watchClient is a Client and FillLister serving the orders and fills set.
+type watchClient struct {
+	Client
+
+	mu     sync.Mutex
+	orders []Order
+	fills  []Fill
+}
+
+func (f *watchClient) Orders() ([]Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Order(nil), f.orders...), nil
+}
+
+func (f *watchClient) Fills(pair Pair, since int64) ([]Fill, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Fill(nil), f.fills...), nil
+}
+
+func TestWatchOrdersGone(t *testing.T) {
+	now := time.Now().Unix()
+	order := Order{Id: 5, Timestamp: now, Type: Buy, Pair: BTC_USD, Price: 100, Remain: 100, Amount: 100}
+	fill := Fill{OrderId: 5, Side: Buy, Pair: BTC_USD, Price: 100, Amount: 100, Timestamp: now}
+	unattributed := fill
+	unattributed.OrderId = 0
+	partial := fill
+	partial.Amount = 40
+
+	tests := []struct {
+		name  string
+		fills []Fill
+		want  OrderEventType
+	}{
+		{"filled", []Fill{fill}, OrderFilled},
+		{"partially filled", []Fill{partial}, OrderCancelled},
+		{"not filled", nil, OrderCancelled},
+		// The fills cannot be told apart from those of other orders.
+		{"unattributed", []Fill{unattributed}, OrderDisappeared},
+	}
+	for _, test := range tests {
+		f := &watchClient{orders: []Order{order}, fills: test.fills}
+		w := WatchOrders(context.Background(), f, PollOptions{Interval: time.Millisecond})
+		if e := <-w.C; e.Type != OrderPlaced || e.Order.Id != order.Id {
+			t.Fatalf("%s: got event %+v, want the order placed", test.name, e)
+		}
+		f.mu.Lock()
+		f.orders = nil
+		f.mu.Unlock()
+		if e := <-w.C; e.Type != test.want || e.Order.Id != order.Id {
+			t.Errorf("%s: got event %+v, want type %d", test.name, e, test.want)
+		}
+		w.Close()
+	}
+}
@@ -0,0 +1,213 @@
+package coincross
+
+import (
+	"context"
+	"time"
+)
+
+// PollOptions configures the polling of PollTicker, PollOrderbook and
+// WatchOrders, and of TailContext as part of TailOptions.
+type PollOptions struct {
+	// The polling interval.
+	Interval time.Duration
+	// Backoff delays the polls after failures, but never below Interval.
+	// DefaultBackoff is used if not set.
+	Backoff BackoffStrategy
+	// ResetOnSuccess resets the backoff at the first successful poll.
+	// Otherwise the backoff recovers gradually, halving the failure count
+	// on every successful poll.
+	ResetOnSuccess bool
+}
+
+// pollBackoff tracks the failures of a polling loop.
+type pollBackoff struct {
+	interval time.Duration
+	backoff  BackoffStrategy
+	reset    bool
+	failures int
+}
+
+func newPollBackoff(opts PollOptions) *pollBackoff {
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	return &pollBackoff{interval: opts.Interval, backoff: backoff, reset: opts.ResetOnSuccess}
+}
+
+// next records the result of a poll, and returns the delay before the next.
+func (p *pollBackoff) next(err error) time.Duration {
+	switch {
+	case err != nil:
+		p.failures++
+	case p.reset:
+		p.failures = 0
+	default:
+		p.failures /= 2
+	}
+	delay := p.interval
+	if p.failures > 0 {
+		if d := p.backoff.Delay(p.failures - 1); d > delay {
+			delay = d
+		}
+	}
+	return delay
+}
+
+// polled records the result of a poll started at start, reports its error,
+// and returns the time left before the next poll.
+func (s *stream) polled(backoff *pollBackoff, err error, start time.Time) time.Duration {
+	if err != nil {
+		s.report(err)
+	}
+	delay := backoff.next(err)
+	s.updateStats(func(stats *StreamStats) {
+		stats.Polls++
+		if err != nil {
+			stats.Errors++
+		}
+		stats.Delay = delay
+	})
+	if delay -= time.Since(start); delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// poll calls fetch every interval, until ctx is done or the stream is
+// closed, and returns ctx.Err() or nil then. The context of fetch is
+// canceled when the stream stops, and the errors it returns are reported.
+func (s *stream) poll(ctx context.Context, opts PollOptions, fetch func(ctx context.Context) error) error {
+	backoff := newPollBackoff(opts)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	pollCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-pollCtx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-s.closing:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		start := time.Now()
+		err := fetch(pollCtx)
+		select {
+		case <-s.closing:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		timer.Reset(s.polled(backoff, err, start))
+	}
+}
+
+// TickerStream follows the ticker of a pair, until it is closed or fails.
+type TickerStream struct {
+	// C delivers the ticker when it changes. Only the latest ticker is kept
+	// if the receiver falls behind. It is closed when the stream stops.
+	C <-chan *Ticker
+
+	stream
+}
+
+// PollTicker polls Client.Ticker, until ctx is done or the stream is closed.
+// Failed polls are reported to Errors, and retried with backoff.
+func PollTicker(ctx context.Context, c Client, pair Pair, opts PollOptions) *TickerStream {
+	tickers := make(chan *Ticker, 1)
+	s := &TickerStream{C: tickers, stream: newStream()}
+	cc := AsContextClient(c)
+	go func() {
+		var last *Ticker
+		err := s.poll(ctx, opts, func(ctx context.Context) error {
+			t, err := cc.TickerContext(ctx, pair)
+			if err != nil || (last != nil && *t == *last) {
+				return err
+			}
+			last = t
+			select {
+			case tickers <- t:
+			default:
+				select {
+				case <-tickers:
+				default:
+				}
+				tickers <- t
+			}
+			return nil
+		})
+		close(tickers)
+		s.finish(err)
+	}()
+	return s
+}
+
+// OrderbookStream follows the orderbook of a pair, until it is closed or
+// fails.
+type OrderbookStream struct {
+	// C delivers the orderbook when it changes. Only the latest orderbook is
+	// kept if the receiver falls behind. It is closed when the stream stops.
+	C <-chan *Orderbook
+
+	stream
+}
+
+// PollOrderbook polls Client.Orderbook, until ctx is done or the stream is
+// closed. Failed polls are reported to Errors, and retried with backoff.
+func PollOrderbook(ctx context.Context, c Client, pair Pair, limit int, opts PollOptions) *OrderbookStream {
+	orderbooks := make(chan *Orderbook, 1)
+	s := &OrderbookStream{C: orderbooks, stream: newStream()}
+	cc := AsContextClient(c)
+	go func() {
+		var last *Orderbook
+		err := s.poll(ctx, opts, func(ctx context.Context) error {
+			o, err := cc.OrderbookContext(ctx, pair, limit)
+			if err != nil || (last != nil && equalOrderbooks(o, last)) {
+				return err
+			}
+			last = o
+			select {
+			case orderbooks <- o:
+			default:
+				select {
+				case <-orderbooks:
+				default:
+				}
+				orderbooks <- o
+			}
+			return nil
+		})
+		close(orderbooks)
+		s.finish(err)
+	}()
+	return s
+}
+
+func equalOrderbooks(a, b *Orderbook) bool {
+	if len(a.Asks) != len(b.Asks) || len(a.Bids) != len(b.Bids) {
+		return false
+	}
+	for i := range a.Asks {
+		if a.Asks[i] != b.Asks[i] {
+			return false
+		}
+	}
+	for i := range a.Bids {
+		if a.Bids[i] != b.Bids[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package coincross
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tickerClient serves Ticker from a list of tickers, repeating the last one.
+type tickerClient struct {
+	Client
+
+	mu      sync.Mutex
+	tickers []Ticker
+}
+
+func (f *tickerClient) Ticker(pair Pair) (*Ticker, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.tickers[0]
+	if len(f.tickers) > 1 {
+		f.tickers = f.tickers[1:]
+	}
+	return &t, nil
+}
+
+func TestPollTicker(t *testing.T) {
+	f := &tickerClient{tickers: []Ticker{{Last: 1}, {Last: 1}, {Last: 2}}}
+	s := PollTicker(context.Background(), f, BTC_USD, PollOptions{Interval: time.Millisecond})
+	// The same ticker is delivered once.
+	for _, want := range []Decimal{1, 2} {
+		if ticker := <-s.C; ticker.Last != want {
+			t.Fatalf("got ticker %s, want %s", ticker.Last, want)
+		}
+	}
+	for s.Stats().Polls < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	s.Close()
+	if _, ok := <-s.C; ok {
+		t.Error("ticker delivered again, or C not closed after Close")
+	}
+}
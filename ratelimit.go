@@ -0,0 +1,204 @@
+package coincross
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EndpointClass tells which budget of the rate limits a request uses.
+type EndpointClass int
+
+const (
+	Public EndpointClass = iota
+	Private
+)
+
+type endpointClassKey struct{}
+
+// WithEndpointClass returns a context marking the requests made with it as
+// of the given class. Requests are public if not marked.
+func WithEndpointClass(ctx context.Context, class EndpointClass) context.Context {
+	return context.WithValue(ctx, endpointClassKey{}, class)
+}
+
+func endpointClassOf(ctx context.Context) EndpointClass {
+	class, _ := ctx.Value(endpointClassKey{}).(EndpointClass)
+	return class
+}
+
+// Limit is a token bucket allowing Rate requests per second on average, and
+// bursts of Burst requests. A Limit with zero Rate is not enforced.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimits are the limits of an exchange.
+type RateLimits struct {
+	Public, Private Limit
+}
+
+// RateLimitMode tells what to do when a budget runs out.
+type RateLimitMode int
+
+const (
+	// Block waits until the request is allowed, or the request is canceled.
+	Block RateLimitMode = iota
+	// FailFast returns an error matching ErrRateLimited immediately.
+	FailFast
+)
+
+// RateLimiter holds a token bucket for each EndpointClass.
+type RateLimiter struct {
+	mode    RateLimitMode
+	buckets map[EndpointClass]*bucket
+}
+
+// NewRateLimiter returns a RateLimiter of its own budgets.
+func NewRateLimiter(limits RateLimits, mode RateLimitMode) *RateLimiter {
+	return &RateLimiter{mode, map[EndpointClass]*bucket{
+		Public:  newBucket(limits.Public),
+		Private: newBucket(limits.Private),
+	}}
+}
+
+var (
+	sharedBucketsMu sync.Mutex
+	sharedBuckets   = make(map[string]*bucket)
+)
+
+func sharedBucket(name string, limit Limit) *bucket {
+	sharedBucketsMu.Lock()
+	defer sharedBucketsMu.Unlock()
+	b, ok := sharedBuckets[name]
+	if !ok {
+		b = newBucket(limit)
+		sharedBuckets[name] = b
+	}
+	return b
+}
+
+// SharedRateLimiter returns a RateLimiter sharing its budgets with the other
+// shared limiters of the exchange: the public budget is shared by all of
+// them, as it is usually limited by IP, and the private budget is shared by
+// the ones with the same key. The limits are taken from the first call.
+func SharedRateLimiter(exchange, key string, limits RateLimits, mode RateLimitMode) *RateLimiter {
+	return &RateLimiter{mode, map[EndpointClass]*bucket{
+		Public:  sharedBucket(exchange, limits.Public),
+		Private: sharedBucket(exchange+"\x00"+key, limits.Private),
+	}}
+}
+
+// Wait takes a token from the budget of class, waiting for it in Block mode.
+func (l *RateLimiter) Wait(ctx context.Context, class EndpointClass) error {
+	b := l.buckets[class]
+	if b == nil {
+		return nil
+	}
+	wait, ok := b.reserve(l.mode == FailFast)
+	if !ok {
+		return fmt.Errorf("%w: %s budget exhausted", ErrRateLimited, class)
+	}
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+type allowedKey struct{}
+
+// Allow waits for the budget of class as Wait does, and returns a context
+// marking the requests made with it as of class and allowed already, so that
+// RateLimitTransport does not wait again. Signed requests are allowed before
+// taking their nonce, so that the requests queued by the limiter are sent in
+// the order of their nonces.
+func (l *RateLimiter) Allow(ctx context.Context, class EndpointClass) (context.Context, error) {
+	if err := l.Wait(ctx, class); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(WithEndpointClass(ctx, class), allowedKey{}, true), nil
+}
+
+type bucket struct {
+	limit  Limit
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit) *bucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &bucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// reserve takes a token, and returns how long to wait before using it.
+// If failFast is set, no token is taken unless one is available now.
+func (b *bucket) reserve(failFast bool) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if burst := float64(b.limit.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 && failFast {
+		return 0, false
+	}
+	// The tokens go negative when reserved ahead, queueing later callers.
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0, true
+	}
+	return time.Duration(-b.tokens / b.limit.Rate * float64(time.Second)), true
+}
+
+// cancel returns a reserved token.
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	b.tokens++
+	b.mu.Unlock()
+}
+
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *RateLimiter
+}
+
+// RateLimitTransport returns a RoundTripper which waits for limiter before
+// sending a request through base, or http.DefaultTransport if base is nil.
+// Mark the requests with WithEndpointClass to use the private budget. The
+// requests made with a context returned by Allow do not wait.
+func RateLimitTransport(base http.RoundTripper, limiter *RateLimiter) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitTransport{base, limiter}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if allowed, _ := ctx.Value(allowedKey{}).(bool); allowed {
+		return t.base.RoundTrip(req)
+	}
+	if err := t.limiter.Wait(ctx, endpointClassOf(ctx)); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
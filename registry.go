@@ -1,33 +1,208 @@
 package coincross
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
+	"sync"
 )
 
-type newClientFunc func(apikey, secret string, transport *http.Transport) Client
+// Config holds the options for creating a client.
+type Config struct {
+	APIKey, Secret string
+	// Transport defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// BaseURL replaces the scheme and host of the exchange endpoints.
+	BaseURL string
+	// Endpoints override the individual endpoints, by the names defined by
+	// each exchange. They take precedence over BaseURL.
+	Endpoints map[string]string
+	// Logger logs every request if set.
+	Logger *log.Logger
+	// RateLimiter defaults to one shared by the clients of the same key,
+	// with the registered rate limits in Block mode.
+	RateLimiter *RateLimiter
+	// NonceSource defaults to one shared by the clients in the process.
+	NonceSource NonceSource
+}
 
-var registry = make(map[string]newClientFunc)
+// Option configures a client created by New.
+type Option func(*Config)
+
+func WithCredentials(apikey, secret string) Option {
+	return func(c *Config) {
+		c.APIKey, c.Secret = apikey, secret
+	}
+}
+
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Config) {
+		c.Transport = transport
+	}
+}
+
+func WithBaseURL(url string) Option {
+	return func(c *Config) {
+		c.BaseURL = url
+	}
+}
+
+// WithEndpoint overrides an endpoint of the exchange.
+func WithEndpoint(name, url string) Option {
+	return func(c *Config) {
+		if c.Endpoints == nil {
+			c.Endpoints = make(map[string]string)
+		}
+		c.Endpoints[name] = url
+	}
+}
+
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Config) {
+		c.RateLimiter = limiter
+	}
+}
+
+// WithRateLimits uses a RateLimiter of its own budgets.
+func WithRateLimits(limits RateLimits, mode RateLimitMode) Option {
+	return WithRateLimiter(NewRateLimiter(limits, mode))
+}
+
+func WithNonceSource(nonce NonceSource) Option {
+	return func(c *Config) {
+		c.NonceSource = nonce
+	}
+}
+
+// Capability names an optional feature of a client.
+type Capability string
+
+const (
+	// The client implements ContextClient.
+	CapContext Capability = "context"
+	// The client implements MarketLister.
+	CapMarkets Capability = "markets"
+	// The client implements OrderGetter.
+	CapGetOrder Capability = "get-order"
+	// The client implements OrderPlacer.
+	CapPlaceOrder Capability = "place-order"
+	// The client implements FillLister.
+	CapFills Capability = "fills"
+)
+
+// Exchange is an entry of the registry.
+type Exchange struct {
+	// The registered name, such as "btce".
+	Name        string
+	DisplayName string
+	// The commonly traded pairs. Use MarketLister for the full list.
+	Pairs        []Pair
+	Capabilities []Capability
+	RateLimits   RateLimits
+	New          func(config *Config) (Client, error)
+}
+
+// Has tells whether the exchange has the capability.
+func (e Exchange) Has(capability Capability) bool {
+	for _, c := range e.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrUnknownExchange = errors.New("unknown exchange")
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Exchange)
+)
+
+// RegisterExchange adds an exchange to the default registry,
+// replacing the one of the same name.
+func RegisterExchange(e Exchange) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[e.Name] = e
+}
+
+// Lookup returns the registered exchange of the name.
+func Lookup(name string) (e Exchange, ok bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	e, ok = registry[name]
+	return
+}
+
+type newClientFunc func(apikey, secret string, transport *http.Transport) Client
 
 // Register a new client to the default registry.
+//
+// Deprecated: use RegisterExchange, which supports all the options.
 func Register(name string, newfunc newClientFunc) {
-	registry[name] = newfunc
+	RegisterExchange(Exchange{
+		Name:        name,
+		DisplayName: name,
+		New: func(c *Config) (Client, error) {
+			transport, ok := c.Transport.(*http.Transport)
+			if (c.Transport != nil && !ok) || c.BaseURL != "" || c.Endpoints != nil || c.Logger != nil || c.RateLimiter != nil || c.NonceSource != nil {
+				return nil, fmt.Errorf("%s: only credentials and *http.Transport are supported", name)
+			}
+			return newfunc(c.APIKey, c.Secret, transport), nil
+		},
+	})
+}
+
+// New creates a client of the registered exchange with the options.
+func New(name string, opts ...Option) (Client, error) {
+	e, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownExchange, name)
+	}
+	config := new(Config)
+	for _, opt := range opts {
+		opt(config)
+	}
+	return e.New(config)
 }
 
-// New creates a client instance with given parameters.
-func New(name string, apikey, secret string, transport *http.Transport) Client {
-	newfunc, ok := registry[name]
-	if ok {
-		return newfunc(apikey, secret, transport)
-	} else {
+// NewWithTransport creates a client with given parameters, as New used to.
+// It returns nil if the client cannot be created.
+func NewWithTransport(name string, apikey, secret string, transport *http.Transport) Client {
+	opts := []Option{WithCredentials(apikey, secret)}
+	if transport != nil {
+		opts = append(opts, WithTransport(transport))
+	}
+	client, err := New(name, opts...)
+	if err != nil {
 		return nil
 	}
+	return client
+}
+
+// DefaultRateLimits returns the registered rate limits of an exchange.
+func DefaultRateLimits(name string) (RateLimits, bool) {
+	e, ok := Lookup(name)
+	return e.RateLimits, ok
 }
 
 // List returns all registered client types.
 func List() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	exchanges := make([]string, 0)
 	for name := range registry {
 		exchanges = append(exchanges, name)
 	}
+	sort.Strings(exchanges)
 	return exchanges
 }
@@ -0,0 +1,278 @@
+package coincross
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes jittered exponential delays.
+type Backoff struct {
+	// The first delay, and the maximum delay, DefaultBackoff.Max if not set.
+	Initial, Max time.Duration
+	// The growth of delays, 2 if not set.
+	Multiplier float64
+	// Randomizes the delays by up to this fraction, i.e. 0.2 for ±20%.
+	Jitter float64
+}
+
+// DefaultBackoff is used when no backoff is given.
+var DefaultBackoff = Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+
+// Delay returns the delay before the given retry, counting from 0.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Initial <= 0 {
+		return 0
+	}
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	max := b.Max
+	if max <= 0 {
+		max = DefaultBackoff.Max
+	}
+	// Capped before converting, as the growth soon overflows a Duration.
+	delay := math.Min(float64(b.Initial)*math.Pow(multiplier, float64(attempt)), float64(max))
+	if b.Jitter > 0 {
+		delay = math.Min(delay*(1+b.Jitter*(2*rand.Float64()-1)), float64(max))
+	}
+	return time.Duration(delay)
+}
+
+// RetryPolicy configures WithRetry.
+type RetryPolicy struct {
+	// The maximum number of attempts, including the first one.
+	Attempts int
+	Backoff  Backoff
+	// Retryable tells whether a failed call may be retried.
+	// IsTemporary is used if not set.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy makes up to 3 attempts on temporary errors.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: DefaultBackoff}
+
+type retryClient struct {
+	Client
+	policy RetryPolicy
+}
+
+// WithRetry returns a Client retrying the calls to c on retryable errors.
+//
+// Only the reads (Balance, Orders, Transactions, Orderbook, History and
+// Ticker) are retried. Cancel is not retried. Trade is never retried blindly:
+// it is placed again at once only after an error telling that the exchange
+// has turned the order down, such as a rate limit or nonce error. After
+// other errors, such as timeouts, the order may have landed: the open orders
+// taken before placing are compared with the new ones, and as an order
+// filled at once does not show in Orders, the fills are searched too if c
+// implements FillLister. The order is placed again only when neither shows
+// it, and an error matching ErrAmbiguousOrderId is returned when it cannot
+// be told.
+func WithRetry(c Client, policy RetryPolicy) Client {
+	if policy.Retryable == nil {
+		policy.Retryable = IsTemporary
+	}
+	return &retryClient{c, policy}
+}
+
+// do calls f until it succeeds, fails for good, or runs out of attempts.
+func (r *retryClient) do(f func() error) (err error) {
+	for attempt := 0; ; attempt++ {
+		err = f()
+		if err == nil || attempt+1 >= r.policy.Attempts || !r.policy.Retryable(err) {
+			return
+		}
+		time.Sleep(r.policy.Backoff.Delay(attempt))
+	}
+}
+
+func (r *retryClient) Balance() (balance map[Symbol]Decimal, err error) {
+	err = r.do(func() (err error) {
+		balance, err = r.Client.Balance()
+		return
+	})
+	return
+}
+
+func (r *retryClient) Trade(tradeType TradeType, pair Pair, price, amount Decimal) (orderId int64, err error) {
+	before, err := r.Orders()
+	if err != nil {
+		return -1, err
+	}
+	known := make(map[int64]bool)
+	for _, o := range before {
+		known[o.Id] = true
+	}
+	var fills func(pair Pair, since int64) ([]Fill, error)
+	if lister, ok := r.Client.(FillLister); ok {
+		fills = func(pair Pair, since int64) (fills []Fill, err error) {
+			err = r.do(func() (err error) {
+				fills, err = lister.Fills(pair, since)
+				return
+			})
+			return
+		}
+	}
+	req := OrderRequest{Side: tradeType, Pair: pair, Price: price, Amount: amount}
+	start := time.Now().Unix()
+	for attempt := 0; ; attempt++ {
+		orderId, err = r.Client.Trade(tradeType, pair, price, amount)
+		if err == nil || attempt+1 >= r.policy.Attempts || !r.policy.Retryable(err) {
+			return
+		}
+		time.Sleep(r.policy.Backoff.Delay(attempt))
+		if rejected(err) {
+			continue
+		}
+
+		order, ferr := findPlaced(r, fills, known, req, start)
+		if ferr != ErrOrderNotFound {
+			if ferr != nil {
+				return -1, ferr
+			}
+			return order.Id, nil
+		}
+	}
+}
+
+// rejected tells whether a failed call is known not to have been carried out.
+func rejected(err error) bool {
+	switch CategoryOf(err) {
+	case CategoryAuth, CategoryPermission, CategoryInsufficientFunds, CategoryRateLimited,
+		CategoryNonce, CategoryInvalidOrder, CategoryUnsupported:
+		return true
+	}
+	return false
+}
+
+// findPlaced searches for an order placed for req at start, in unix seconds,
+// whose reply was lost. The orders of known are not taken for it.
+//
+// The new open orders are matched by FindPlacedOrder. As an order filled at
+// once does not show in Orders, the fills since start are searched too if
+// fills is not nil, for the only order filled at the price asked or better.
+// It returns ErrOrderNotFound only if there is surely no such order, and an
+// error matching ErrAmbiguousOrderId if it cannot be told.
+func findPlaced(c Client, fills func(pair Pair, since int64) ([]Fill, error), known map[int64]bool, req OrderRequest, start int64) (Order, error) {
+	orders, err := c.Orders()
+	if err != nil {
+		return Order{}, fmt.Errorf("%w: %v", ErrAmbiguousOrderId, err)
+	}
+	var after []Order
+	for _, o := range orders {
+		if !known[o.Id] && (o.Timestamp == 0 || o.Timestamp >= start-clockSkew) {
+			after = append(after, o)
+		}
+	}
+	order, err := FindPlacedOrder(nil, after, req.Side, req.Pair, req.Price, req.Amount)
+	if err != ErrOrderNotFound {
+		return order, err
+	}
+
+	if fills == nil {
+		return Order{}, fmt.Errorf("%w: the order may have been filled", ErrAmbiguousOrderId)
+	}
+	recent, err := fills(req.Pair, start-clockSkew)
+	if err != nil {
+		return Order{}, fmt.Errorf("%w: the order may have been filled: %v", ErrAmbiguousOrderId, err)
+	}
+	filled := make(map[int64]Decimal)
+	for _, f := range recent {
+		if known[f.OrderId] || f.Side != req.Side || f.Pair != req.Pair || (req.Type == LimitOrder &&
+			((req.Side == Buy && f.Price > req.Price) || (req.Side == Sell && f.Price < req.Price))) {
+			continue
+		}
+		filled[f.OrderId] += f.Amount
+	}
+	// Fills without an order id cannot be told from those of other orders.
+	switch _, unattributed := filled[0]; {
+	case len(filled) == 0:
+		return Order{}, ErrOrderNotFound
+	case len(filled) == 1 && !unattributed:
+		for id, amount := range filled {
+			order = req.Order(id)
+			order.Remain, order.Status = order.Amount-amount, StatusUnknown
+			if amount >= order.Amount {
+				order.Remain, order.Status = 0, StatusFilled
+			}
+			return order, nil
+		}
+	}
+	return Order{}, fmt.Errorf("%w: %d orders filled meanwhile", ErrAmbiguousOrderId, len(filled))
+}
+
+func (r *retryClient) Orders() (orders []Order, err error) {
+	err = r.do(func() (err error) {
+		orders, err = r.Client.Orders()
+		return
+	})
+	return
+}
+
+func (r *retryClient) Transactions(limit int) (transactions []Transaction, err error) {
+	err = r.do(func() (err error) {
+		transactions, err = r.Client.Transactions(limit)
+		return
+	})
+	return
+}
+
+func (r *retryClient) Orderbook(pair Pair, limit int) (orderbook *Orderbook, err error) {
+	err = r.do(func() (err error) {
+		orderbook, err = r.Client.Orderbook(pair, limit)
+		return
+	})
+	return
+}
+
+func (r *retryClient) History(pair Pair, since int64) (trades []Trade, next int64, err error) {
+	err = r.do(func() (err error) {
+		trades, next, err = r.Client.History(pair, since)
+		return
+	})
+	return
+}
+
+func (r *retryClient) Ticker(pair Pair) (ticker *Ticker, err error) {
+	err = r.do(func() (err error) {
+		ticker, err = r.Client.Ticker(pair)
+		return
+	})
+	return
+}
+
+// FindPlacedOrder finds an order placed between two snapshots of the orders.
+//
+// Among the new orders of the same type and pair, the one with the same price
+// and amount is returned. If none matches exactly, the only new order is
+// returned, as the exchange may have truncated the price or amount. It
+// returns ErrOrderNotFound if there is no new order, or an error matching
+// ErrAmbiguousOrderId if there are several candidates.
+func FindPlacedOrder(before, after []Order, tradeType TradeType, pair Pair, price, amount Decimal) (Order, error) {
+	known := make(map[int64]bool)
+	for _, o := range before {
+		known[o.Id] = true
+	}
+	var placed, exact []Order
+	for _, o := range after {
+		if known[o.Id] || o.Type != tradeType || o.Pair != pair {
+			continue
+		}
+		placed = append(placed, o)
+		if o.Price == price && o.Amount == amount {
+			exact = append(exact, o)
+		}
+	}
+	switch {
+	case len(exact) == 1:
+		return exact[0], nil
+	case len(placed) == 1:
+		return placed[0], nil
+	case len(placed) == 0:
+		return Order{}, ErrOrderNotFound
+	}
+	return Order{}, fmt.Errorf("%w: %d new orders found", ErrAmbiguousOrderId, len(placed))
+}
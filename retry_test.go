@@ -0,0 +1,166 @@
+package coincross
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// tradeClient is a Client and FillLister whose Trade is scripted by place.
+type tradeClient struct {
+	Client
+
+	orders []Order
+	fills  []Fill
+	calls  int
+	// The since of the last call to Fills.
+	since int64
+	// place is called by Trade with the number of the call, counting from 1.
+	place func(f *tradeClient, call int) (int64, error)
+}
+
+func (f *tradeClient) Trade(tradeType TradeType, pair Pair, price, amount Decimal) (int64, error) {
+	f.calls++
+	return f.place(f, f.calls)
+}
+
+func (f *tradeClient) Orders() ([]Order, error) {
+	return append([]Order(nil), f.orders...), nil
+}
+
+func (f *tradeClient) Fills(pair Pair, since int64) ([]Fill, error) {
+	f.since = since
+	var fills []Fill
+	for _, fill := range f.fills {
+		if fill.Timestamp >= since {
+			fills = append(fills, fill)
+		}
+	}
+	return fills, nil
+}
+
+var (
+	errTimeout     = &ExchangeError{Method: "Trade", Message: "timeout", Category: CategoryTemporary}
+	errRateLimited = &ExchangeError{Method: "Trade", Message: "too many requests", Category: CategoryRateLimited}
+)
+
+var testPolicy = RetryPolicy{Attempts: 3, Backoff: Backoff{Initial: time.Millisecond}}
+
+func newTradeClient(place func(f *tradeClient, call int) (int64, error)) *tradeClient {
+	// An order placed before, which is never taken for the new one.
+	return &tradeClient{
+		orders: []Order{{Id: 1, Type: Buy, Pair: BTC_USD, Price: 100, Amount: 100}},
+		place:  place,
+	}
+}
+
+func TestRetryTradeRejected(t *testing.T) {
+	f := newTradeClient(func(f *tradeClient, call int) (int64, error) {
+		if call == 1 {
+			return -1, errRateLimited
+		}
+		return 7, nil
+	})
+	id, err := WithRetry(f, testPolicy).Trade(Buy, BTC_USD, 100, 100)
+	if id != 7 || err != nil || f.calls != 2 {
+		t.Errorf("got %d, %v after %d calls, want 7 after 2", id, err, f.calls)
+	}
+}
+
+func TestRetryTradeNotLanded(t *testing.T) {
+	f := newTradeClient(func(f *tradeClient, call int) (int64, error) {
+		if call == 1 {
+			return -1, errTimeout
+		}
+		return 7, nil
+	})
+	id, err := WithRetry(f, testPolicy).Trade(Buy, BTC_USD, 100, 100)
+	if id != 7 || err != nil || f.calls != 2 {
+		t.Errorf("got %d, %v after %d calls, want 7 after 2", id, err, f.calls)
+	}
+}
+
+func TestRetryTradeOpen(t *testing.T) {
+	f := newTradeClient(func(f *tradeClient, call int) (int64, error) {
+		// Landed, but the reply was lost. The amount was truncated.
+		f.orders = append(f.orders, Order{Id: 5, Type: Buy, Pair: BTC_USD, Price: 100, Amount: 99})
+		return -1, errTimeout
+	})
+	id, err := WithRetry(f, testPolicy).Trade(Buy, BTC_USD, 100, 100)
+	if id != 5 || err != nil || f.calls != 1 {
+		t.Errorf("got %d, %v after %d calls, want 5 after 1", id, err, f.calls)
+	}
+}
+
+func TestRetryTradeFilled(t *testing.T) {
+	f := newTradeClient(func(f *tradeClient, call int) (int64, error) {
+		// Filled at once, as timed by an exchange clock running behind.
+		now := time.Now().Unix()
+		f.fills = append(f.fills,
+			Fill{OrderId: 9, Side: Buy, Pair: BTC_USD, Price: 90, Amount: 100, Timestamp: now - clockSkew/2},
+			// Fills of other orders.
+			Fill{OrderId: 1, Side: Buy, Pair: BTC_USD, Price: 100, Amount: 1, Timestamp: now},
+			Fill{OrderId: 10, Side: Sell, Pair: BTC_USD, Price: 100, Amount: 1, Timestamp: now},
+			Fill{OrderId: 11, Side: Buy, Pair: BTC_USD, Price: 110, Amount: 1, Timestamp: now},
+			Fill{OrderId: 12, Side: Buy, Pair: BTC_USD, Price: 90, Amount: 1, Timestamp: now - 2*clockSkew},
+		)
+		return -1, errTimeout
+	})
+	start := time.Now().Unix()
+	id, err := WithRetry(f, testPolicy).Trade(Buy, BTC_USD, 100, 100)
+	if id != 9 || err != nil || f.calls != 1 {
+		t.Errorf("got %d, %v after %d calls, want 9 after 1", id, err, f.calls)
+	}
+	if f.since > start-clockSkew {
+		t.Errorf("fills searched since %d, want at most %d", f.since, start-clockSkew)
+	}
+}
+
+func TestRetryTradeAmbiguous(t *testing.T) {
+	tests := []struct {
+		name  string
+		place func(f *tradeClient, call int) (int64, error)
+	}{
+		{"orders", func(f *tradeClient, call int) (int64, error) {
+			f.orders = append(f.orders,
+				Order{Id: 5, Type: Buy, Pair: BTC_USD, Price: 100, Amount: 99},
+				Order{Id: 6, Type: Buy, Pair: BTC_USD, Price: 100, Amount: 98})
+			return -1, errTimeout
+		}},
+		{"fills", func(f *tradeClient, call int) (int64, error) {
+			now := time.Now().Unix()
+			f.fills = append(f.fills,
+				Fill{OrderId: 8, Side: Buy, Pair: BTC_USD, Price: 100, Amount: 100, Timestamp: now},
+				Fill{OrderId: 9, Side: Buy, Pair: BTC_USD, Price: 100, Amount: 100, Timestamp: now})
+			return -1, errTimeout
+		}},
+		{"fills without order ids", func(f *tradeClient, call int) (int64, error) {
+			f.fills = append(f.fills,
+				Fill{Side: Buy, Pair: BTC_USD, Price: 100, Amount: 100, Timestamp: time.Now().Unix()})
+			return -1, errTimeout
+		}},
+	}
+	for _, test := range tests {
+		f := newTradeClient(test.place)
+		_, err := WithRetry(f, testPolicy).Trade(Buy, BTC_USD, 100, 100)
+		if !errors.Is(err, ErrAmbiguousOrderId) || f.calls != 1 {
+			t.Errorf("%s: got %v after %d calls, want ErrAmbiguousOrderId after 1", test.name, err, f.calls)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Jitter: 0.5}
+	for _, attempt := range []int{0, 10, 100, 10000} {
+		if d := b.Delay(attempt); d <= 0 || d > DefaultBackoff.Max {
+			t.Errorf("attempt %d: got delay %v, want in (0, %v]", attempt, d, DefaultBackoff.Max)
+		}
+	}
+	b = Backoff{Initial: time.Second, Max: 10 * time.Second}
+	if d := b.Delay(2); d != 4*time.Second {
+		t.Errorf("got delay %v, want 4s", d)
+	}
+	if d := b.Delay(1000); d != b.Max {
+		t.Errorf("got delay %v, want %v", d, b.Max)
+	}
+}
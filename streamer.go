@@ -0,0 +1,137 @@
+package coincross
+
+import (
+	"sync"
+	"time"
+)
+
+// StreamStats are the statistics of a polling stream.
+type StreamStats struct {
+	// The numbers of polls made, of the failed ones, and of the gaps found.
+	Polls, Errors, Gaps int64
+	// The current delay between polls.
+	Delay time.Duration
+}
+
+// stream is the lifecycle shared by the polling streams.
+type stream struct {
+	errors  chan error
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	err     error
+
+	statsMu sync.Mutex
+	stats   StreamStats
+}
+
+func newStream() stream {
+	return stream{
+		errors:  make(chan error, 16),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Errors delivers the transient errors, such as failed polls, which the
+// stream recovers from. Errors not received in time are dropped.
+// The channel is closed when the stream stops.
+func (s *stream) Errors() <-chan error {
+	return s.errors
+}
+
+// Err returns the error that stopped the stream, once it has stopped.
+// It returns nil if the stream is stopped by Close.
+func (s *stream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Done is closed when the stream has stopped.
+func (s *stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Stats returns the current statistics.
+func (s *stream) Stats() StreamStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	return s.stats
+}
+
+func (s *stream) updateStats(update func(stats *StreamStats)) {
+	s.statsMu.Lock()
+	update(&s.stats)
+	s.statsMu.Unlock()
+}
+
+// Close stops the stream, and waits until it has stopped.
+// It is safe to call Close more than once.
+func (s *stream) Close() error {
+	s.once.Do(func() { close(s.closing) })
+	<-s.done
+	return nil
+}
+
+// report sends a transient error, dropping it if nobody is listening.
+func (s *stream) report(err error) {
+	select {
+	case s.errors <- err:
+	default:
+	}
+}
+
+// finish is called by the goroutine feeding the stream when it returns,
+// after closing its own channels.
+func (s *stream) finish(err error) {
+	s.err = err
+	close(s.errors)
+	close(s.done)
+}
+
+// Streamer follows a stream of trades, until it is closed or fails.
+type Streamer struct {
+	// C delivers the trades. It is closed when the streamer stops.
+	C <-chan Trade
+
+	stream
+	gaps chan Gap
+}
+
+func newStreamer(c <-chan Trade) *Streamer {
+	return &Streamer{
+		C:      c,
+		stream: newStream(),
+		gaps:   make(chan Gap, 16),
+	}
+}
+
+// Gaps delivers the gaps found in the stream, where some trades may be
+// missing. Gaps not received in time are dropped, but still counted in
+// Stats. The channel is closed when the streamer stops.
+func (s *Streamer) Gaps() <-chan Gap {
+	return s.gaps
+}
+
+// reportGap sends a gap, dropping it if nobody is listening.
+func (s *Streamer) reportGap(gap Gap) {
+	s.updateStats(func(stats *StreamStats) {
+		stats.Gaps++
+	})
+	select {
+	case s.gaps <- gap:
+	default:
+	}
+}
+
+// stop is called by the goroutine feeding the streamer when it returns,
+// after which it must not send to any of the channels.
+func (s *Streamer) stop(c chan<- Trade, err error) {
+	close(c)
+	close(s.gaps)
+	s.finish(err)
+}
@@ -1,54 +1,112 @@
 package coincross
 
 import (
-	"log"
+	"context"
 	"time"
 )
 
+// BackoffStrategy computes the delay after the given number of consecutive
+// failures, counting from 0. Backoff is a BackoffStrategy.
+type BackoffStrategy interface {
+	Delay(failures int) time.Duration
+}
+
+// JoinMode tells how the batches returned by Client.History join onto each
+// other. It is used to detect gaps, and to make checkpoints.
+type JoinMode int
+
+const (
+	// The cursor is the last trade id, and trade ids are consecutive,
+	// as on BTCChina.
+	JoinByID JoinMode = iota
+	// The cursor is the timestamp of the last trade, as on BTC-E.
+	// A batch joins if it overlaps the previous one in time.
+	JoinByTimestamp
+)
+
+// Gap tells that some trades may be missing between two trades, usually
+// because more trades happened between two polls than a poll returns.
+type Gap struct {
+	Pair Pair
+	// The last trade before the gap, and the first one after it.
+	After, Before Trade
+}
+
+// TailOptions configures TailContext.
+type TailOptions struct {
+	PollOptions
+	Join JoinMode
+	// Checkpoint resumes the stream from the saved position, ignoring
+	// since, and saves the position after every trade received from C,
+	// which is unbuffered then, so no trade waiting in C is checkpointed.
+	Checkpoint Checkpointer
+}
+
 // Tail follows Client.History.
+// Failed polls are reported to Streamer.Errors, and retried with backoff.
 func Tail(c Client, pair Pair, since int64, interval time.Duration) *Streamer {
+	return TailContext(context.Background(), c, pair, since, TailOptions{PollOptions: PollOptions{Interval: interval}})
+}
+
+// TailContext is like Tail, and stops when ctx is done, with Streamer.Err
+// returning ctx.Err(). The polls are canceled as well if c implements
+// ContextClient. Gaps in the stream are reported to Streamer.Gaps.
+func TailContext(ctx context.Context, c Client, pair Pair, since int64, opts TailOptions) *Streamer {
 	// Advanced Go Concurrency Patterns: http://talks.golang.org/2013/advconc.slide
 
+	type fetchResult struct {
+		trades []Trade
+		next   int64
+		err    error
+	}
+
+	buffer := 100
+	if opts.Checkpoint != nil {
+		buffer = 0
+	}
 	var (
-		trades  = make(chan Trade, 100)
-		closing = make(chan bool)
+		trades = make(chan Trade, buffer)
+		s      = newStreamer(trades)
+		cc     = AsContextClient(c)
 
 		tid     = int64(-1)
+		last    Trade
+		hasLast = false
 		timer   = time.NewTimer(0)
-		backoff = interval
-		fetched = make(chan []Trade)
+		backoff = newPollBackoff(opts.PollOptions)
 		pending []Trade
 	)
-
-	fetch := func() {
-		start := time.Now()
-		if history, next, err := c.History(pair, since); err == nil {
-			filtered := []Trade{}
-			for _, t := range history {
-				if t.Id > tid {
-					filtered = append(filtered, t)
-					tid = t.Id
-				}
-			}
-			fetched <- filtered
-
-			since = next
-			if backoff > interval {
-				backoff = backoff / 2
-			}
-		} else {
-			backoff *= 2
-			log.Printf("Error getting history: %s", err.Error())
-			log.Printf("Waiting for %v before retrying...", backoff)
+	if opts.Checkpoint != nil {
+		cp, ok, err := opts.Checkpoint.LoadCheckpoint()
+		if err != nil {
+			s.stop(trades, err)
+			return s
+		}
+		if ok {
+			since, tid = cp.Since, cp.LastId
+			last, hasLast = Trade{Id: cp.LastId, Timestamp: cp.Timestamp, Pair: pair}, true
 		}
-
-		dur := time.Now().Sub(start)
-		timer.Reset(backoff - dur)
 	}
+	pollCtx, cancel := context.WithCancel(ctx)
 
 	go func() {
-		var first Trade
-		var updates chan Trade
+		var (
+			err      error
+			first    Trade
+			updates  chan Trade
+			timeout  = timer.C
+			fetching chan fetchResult
+			start    time.Time
+		)
+		defer func() { s.stop(trades, err) }()
+		defer timer.Stop()
+		defer func() {
+			// So that no poll outlives Close.
+			cancel()
+			if fetching != nil {
+				<-fetching
+			}
+		}()
 
 		for {
 			if len(pending) > 0 {
@@ -59,19 +117,70 @@ func Tail(c Client, pair Pair, since int64, interval time.Duration) *Streamer {
 			}
 
 			select {
-			case <-timer.C:
-				go fetch()
-			case history := <-fetched:
-				pending = append(pending, history...)
+			case <-timeout:
+				timeout = nil
+				start = time.Now()
+				// Buffered, so the fetch never blocks if we are closed meanwhile.
+				fetching = make(chan fetchResult, 1)
+				go func(done chan<- fetchResult, since int64) {
+					history, next, err := cc.HistoryContext(pollCtx, pair, since)
+					done <- fetchResult{history, next, err}
+				}(fetching, since)
+			case r := <-fetching:
+				fetching = nil
+				if r.err == nil {
+					checked := !hasLast
+					for _, t := range r.trades {
+						if t.Id <= tid {
+							if opts.Join == JoinByTimestamp {
+								// Overlapping in time, so nothing is missed.
+								checked = true
+							}
+							continue
+						}
+						if !checked && !opts.Join.joins(last, t) {
+							s.reportGap(Gap{pair, last, t})
+						}
+						checked = true
+						pending = append(pending, t)
+						tid, last, hasLast = t.Id, t, true
+					}
+					since = r.next
+				}
+				timer.Reset(s.polled(backoff, r.err, start))
+				timeout = timer.C
 			case updates <- first:
 				pending = pending[1:]
-			case <-closing:
-				close(trades)
-				timer.Stop()
-				break
+				if opts.Checkpoint != nil {
+					if err := opts.Checkpoint.SaveCheckpoint(opts.Join.checkpoint(first)); err != nil {
+						s.report(err)
+					}
+				}
+			case <-s.closing:
+				return
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
 			}
 		}
 	}()
 
-	return &Streamer{C: trades, Closing: closing}
+	return s
+}
+
+// joins tells whether t follows last without any trade in between.
+func (j JoinMode) joins(last, t Trade) bool {
+	if j == JoinByTimestamp {
+		return t.Timestamp <= last.Timestamp
+	}
+	return t.Id == last.Id+1
+}
+
+// checkpoint returns the position after t.
+func (j JoinMode) checkpoint(t Trade) Checkpoint {
+	since := t.Id
+	if j == JoinByTimestamp {
+		since = t.Timestamp
+	}
+	return Checkpoint{Since: since, LastId: t.Id, Timestamp: t.Timestamp}
 }
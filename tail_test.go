@@ -0,0 +1,208 @@
+package coincross
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient serves History from a list of trades, with consecutive ids.
+type fakeClient struct {
+	Client
+	ContextClient
+
+	mu     sync.Mutex
+	trades []Trade
+	// Errors returned by the next polls, in order.
+	errs []error
+	// Block makes the polls wait until their context is done.
+	block    bool
+	inflight int
+}
+
+func newFakeClient(n int) *fakeClient {
+	f := new(fakeClient)
+	for i := 1; i <= n; i++ {
+		f.trades = append(f.trades, Trade{Id: int64(i), Timestamp: int64(i), Pair: BTC_USD, Price: 1, Amount: 1})
+	}
+	return f
+}
+
+func (f *fakeClient) History(pair Pair, since int64) ([]Trade, int64, error) {
+	return f.HistoryContext(context.Background(), pair, since)
+}
+
+func (f *fakeClient) HistoryContext(ctx context.Context, pair Pair, since int64) ([]Trade, int64, error) {
+	f.mu.Lock()
+	f.inflight++
+	block := f.block
+	var err error
+	if len(f.errs) > 0 {
+		err, f.errs = f.errs[0], f.errs[1:]
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inflight--
+		f.mu.Unlock()
+	}()
+
+	if block {
+		<-ctx.Done()
+		// Slow to return, as a request being torn down.
+		time.Sleep(20 * time.Millisecond)
+		return nil, since, ctx.Err()
+	}
+	if err != nil {
+		return nil, since, err
+	}
+	var trades []Trade
+	for _, t := range f.trades {
+		if t.Id > since {
+			trades = append(trades, t)
+		}
+	}
+	if len(trades) > 0 {
+		since = trades[len(trades)-1].Id
+	}
+	return trades, since, nil
+}
+
+func (f *fakeClient) Inflight() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inflight
+}
+
+// checkGoroutines fails if the number of goroutines does not get back to n.
+func checkGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left, want %d", runtime.NumGoroutine(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTailDelivers(t *testing.T) {
+	f := newFakeClient(5)
+	s := TailContext(context.Background(), f, BTC_USD, 0, TailOptions{PollOptions: PollOptions{Interval: time.Millisecond}})
+	defer s.Close()
+	for i := int64(1); i <= 5; i++ {
+		if trade := <-s.C; trade.Id != i {
+			t.Fatalf("got trade %d, want %d", trade.Id, i)
+		}
+	}
+}
+
+func TestTailErrors(t *testing.T) {
+	f := newFakeClient(1)
+	fetchErr := errors.New("fetch failed")
+	f.errs = []error{fetchErr}
+	s := TailContext(context.Background(), f, BTC_USD, 0, TailOptions{PollOptions: PollOptions{
+		Interval: time.Millisecond,
+		Backoff:  Backoff{Initial: time.Millisecond},
+	}})
+	defer s.Close()
+	if err := <-s.Errors(); err != fetchErr {
+		t.Fatalf("got error %v, want %v", err, fetchErr)
+	}
+	// Recovered after the error.
+	if trade := <-s.C; trade.Id != 1 {
+		t.Fatalf("got trade %d, want 1", trade.Id)
+	}
+	if stats := s.Stats(); stats.Errors != 1 {
+		t.Errorf("got %d errors in stats, want 1", stats.Errors)
+	}
+}
+
+func TestTailCloseWaits(t *testing.T) {
+	f := newFakeClient(0)
+	f.block = true
+	s := TailContext(context.Background(), f, BTC_USD, 0, TailOptions{PollOptions: PollOptions{Interval: time.Millisecond}})
+	for f.Inflight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.Close()
+	if n := f.Inflight(); n != 0 {
+		t.Errorf("%d polls in flight after Close", n)
+	}
+	if _, ok := <-s.C; ok {
+		t.Error("C not closed after Close")
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("got Err %v after Close, want nil", err)
+	}
+}
+
+func TestTailNoLeakAfterClose(t *testing.T) {
+	n := runtime.NumGoroutine()
+	f := newFakeClient(3)
+	s := TailContext(context.Background(), f, BTC_USD, 0, TailOptions{PollOptions: PollOptions{Interval: time.Millisecond}})
+	<-s.C
+	s.Close()
+	checkGoroutines(t, n)
+}
+
+func TestTailNoLeakAfterCancel(t *testing.T) {
+	n := runtime.NumGoroutine()
+	f := newFakeClient(0)
+	f.block = true
+	ctx, cancel := context.WithCancel(context.Background())
+	s := TailContext(ctx, f, BTC_USD, 0, TailOptions{PollOptions: PollOptions{Interval: time.Millisecond}})
+	for f.Inflight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-s.Done()
+	if err := s.Err(); err != context.Canceled {
+		t.Errorf("got Err %v, want %v", err, context.Canceled)
+	}
+	checkGoroutines(t, n)
+}
+
+// memoryCheckpoint is a Checkpointer kept in memory.
+type memoryCheckpoint struct {
+	mu    sync.Mutex
+	cp    Checkpoint
+	saved bool
+}
+
+func (m *memoryCheckpoint) LoadCheckpoint() (Checkpoint, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cp, m.saved, nil
+}
+
+func (m *memoryCheckpoint) SaveCheckpoint(cp Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cp, m.saved = cp, true
+	return nil
+}
+
+func TestTailCheckpoint(t *testing.T) {
+	f := newFakeClient(5)
+	cp := new(memoryCheckpoint)
+	s := TailContext(context.Background(), f, BTC_USD, 0, TailOptions{PollOptions: PollOptions{Interval: time.Millisecond}, Checkpoint: cp})
+	<-s.C
+	<-s.C
+	// Give the streamer time to checkpoint trades not received, if it did.
+	time.Sleep(20 * time.Millisecond)
+	s.Close()
+	if saved, _, _ := cp.LoadCheckpoint(); saved.LastId != 2 {
+		t.Fatalf("checkpointed trade %d, want 2", saved.LastId)
+	}
+
+	// Resumes after the last received trade.
+	s = TailContext(context.Background(), f, BTC_USD, 0, TailOptions{PollOptions: PollOptions{Interval: time.Millisecond}, Checkpoint: cp})
+	defer s.Close()
+	if trade := <-s.C; trade.Id != 3 {
+		t.Fatalf("resumed at trade %d, want 3", trade.Id)
+	}
+}
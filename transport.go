@@ -1,8 +1,11 @@
 package coincross
 
 import (
+	"log"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -24,3 +27,47 @@ func TimeoutTransport(connectTimeout time.Duration, totalTimeout time.Duration)
 		Dial: timeoutDialer(connectTimeout, totalTimeout),
 	}
 }
+
+type logTransport struct {
+	base   http.RoundTripper
+	logger *log.Logger
+}
+
+// LogTransport returns a RoundTripper logging every request sent through
+// base, or http.DefaultTransport if base is nil.
+func LogTransport(base http.RoundTripper, logger *log.Logger) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &logTransport{base, logger}
+}
+
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.Printf("%s %s: %v (%v)", req.Method, req.URL, err, time.Since(start))
+	} else {
+		t.logger.Printf("%s %s: %s (%v)", req.Method, req.URL, res.Status, time.Since(start))
+	}
+	return res, err
+}
+
+// RebaseURL replaces the scheme and host of endpoint with those of base, and
+// prefixes the path of base. The endpoint is returned as is if base is empty.
+func RebaseURL(endpoint, base string) string {
+	if base == "" {
+		return endpoint
+	}
+	e, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return endpoint
+	}
+	e.Scheme, e.Host = b.Scheme, b.Host
+	e.Path = strings.TrimRight(b.Path, "/") + e.Path
+	return e.String()
+}